@@ -0,0 +1,657 @@
+package astequal
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+)
+
+// Alpha-equivalence mode support.
+//
+// Locally bound identifiers are tracked as a stack of x-y name pairs.
+// A pair is pushed at the binding site (parameter, := variable, etc.)
+// and popped when the enclosing scope ends.
+// Two identifiers are equal if they are bound by the same pair
+// or if both are free and have the same name.
+
+// binding is a pair of identifiers names bound by the same declaration.
+type binding struct {
+	x string
+	y string
+}
+
+// scope is a saved comparer scope state.
+type scope struct {
+	bindings int
+	start    int
+}
+
+func (c *comparer) enterScope() scope {
+	s := scope{bindings: len(c.bindings), start: c.scopeStart}
+	c.scopeStart = len(c.bindings)
+	return s
+}
+
+func (c *comparer) leaveScope(s scope) {
+	c.bindings = c.bindings[:s.bindings]
+	c.scopeStart = s.start
+}
+
+// enterFunc starts a new function scope.
+// Labels are scoped to the function body.
+func (c *comparer) enterFunc() (scope, []binding) {
+	labels := c.labels
+	c.labels = nil
+	return c.enterScope(), labels
+}
+
+func (c *comparer) leaveFunc(s scope, labels []binding) {
+	c.leaveScope(s)
+	c.labels = labels
+}
+
+// bind declares x and y as names of the same local entity.
+// Blank identifiers are never bound and only match each other.
+func (c *comparer) bind(x, y *ast.Ident) bool {
+	if x == nil || y == nil {
+		return x == y
+	}
+	if x.Name == "_" || y.Name == "_" {
+		return x.Name == y.Name
+	}
+	c.bindings = append(c.bindings, binding{x: x.Name, y: y.Name})
+	return true
+}
+
+// lookup returns the indexes of the innermost x and y bindings
+// starting from the given index. -1 is returned for unbound names.
+func (c *comparer) lookup(x, y string, from int) (xi, yi int) {
+	xi, yi = -1, -1
+	for i := len(c.bindings) - 1; i >= from && (xi == -1 || yi == -1); i-- {
+		if xi == -1 && c.bindings[i].x == x {
+			xi = i
+		}
+		if yi == -1 && c.bindings[i].y == y {
+			yi = i
+		}
+	}
+	return xi, yi
+}
+
+// boundEq compares x and y identifiers by their bindings.
+//
+// Reports false as the second result if both identifiers are free.
+func (c *comparer) boundEq(x, y *ast.Ident) (equal, ok bool) {
+	if c.plainNames || len(c.bindings) == 0 {
+		return false, false
+	}
+	xi, yi := c.lookup(x.Name, y.Name, 0)
+	if xi == -1 && yi == -1 {
+		return false, false
+	}
+	return xi == yi, true
+}
+
+// nameEq compares identifiers that can't refer to local bindings,
+// like selectors and struct field names.
+func (c *comparer) nameEq(x, y *ast.Ident) bool {
+	if !c.alpha || c.plainNames {
+		return c.astIdentEq(x, y)
+	}
+	c.plainNames = true
+	eq := c.astIdentEq(x, y)
+	c.plainNames = false
+	return eq
+}
+
+func (c *comparer) nameSliceEq(xs, ys []*ast.Ident) bool {
+	if len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		if !c.nameEq(xs[i], ys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// keyEq compares composite literal keys.
+// An identifier key is either a struct field name or a map key expression,
+// so it's equal if it matches in either sense.
+func (c *comparer) keyEq(x, y ast.Expr) bool {
+	if c.alpha {
+		xid, ok1 := x.(*ast.Ident)
+		yid, ok2 := y.(*ast.Ident)
+		if ok1 && ok2 && xid != nil && yid != nil {
+			return c.astIdentEq(xid, yid) || c.nameEq(xid, yid)
+		}
+	}
+	return c.astExprEq(x, y)
+}
+
+// labelEq compares labels.
+// Labels are consistently renamed within a function.
+func (c *comparer) labelEq(x, y *ast.Ident) bool {
+	if !c.alpha {
+		return c.astIdentEq(x, y)
+	}
+	if x == nil || y == nil {
+		return x == y
+	}
+	for _, l := range c.labels {
+		if l.x == x.Name || l.y == y.Name {
+			return l.x == x.Name && l.y == y.Name
+		}
+	}
+	c.labels = append(c.labels, binding{x: x.Name, y: y.Name})
+	return true
+}
+
+// defineEq compares x and y expressions on the left side of :=.
+// New variables are bound, redeclared ones are compared as usual.
+func (c *comparer) defineEq(x, y ast.Expr) bool {
+	xid, ok1 := x.(*ast.Ident)
+	yid, ok2 := y.(*ast.Ident)
+	if !ok1 || !ok2 || xid == nil || yid == nil {
+		return c.astExprEq(x, y)
+	}
+	xi, yi := c.lookup(xid.Name, yid.Name, c.scopeStart)
+	if xi == -1 && yi == -1 {
+		return c.bind(xid, yid)
+	}
+	return xi == yi
+}
+
+func (c *comparer) defineSliceEq(xs, ys []ast.Expr) bool {
+	if len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		if !c.defineEq(xs[i], ys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// bindFieldListEq compares parameters, results or type parameters lists
+// and binds their names.
+//
+// Type parameters are bound before their constraints are compared,
+// as constraints may refer to them.
+func (c *comparer) bindFieldListEq(x, y *ast.FieldList, typeParams bool) bool {
+	if x == nil || y == nil {
+		return x == y
+	}
+	if len(x.List) != len(y.List) {
+		return false
+	}
+	for i := range x.List {
+		xf, yf := x.List[i], y.List[i]
+		if xf == nil || yf == nil {
+			if xf != yf {
+				return false
+			}
+			continue
+		}
+		if len(xf.Names) != len(yf.Names) {
+			return false
+		}
+		if !typeParams && !c.astExprEq(xf.Type, yf.Type) {
+			return false
+		}
+		for j := range xf.Names {
+			if !c.bind(xf.Names[j], yf.Names[j]) {
+				return false
+			}
+		}
+		if typeParams && !c.astExprEq(xf.Type, yf.Type) {
+			return false
+		}
+		if !c.tagsEq(xf.Tag, yf.Tag) ||
+			!c.commentsEq(xf.Doc, yf.Doc) ||
+			!c.commentsEq(xf.Comment, yf.Comment) {
+			return false
+		}
+	}
+	return true
+}
+
+// signatureEq compares function types and binds their parameters.
+func (c *comparer) signatureEq(x, y *ast.FuncType) bool {
+	if x == nil || y == nil {
+		return x == y
+	}
+	return c.bindFieldListEq(forFuncType(x), forFuncType(y), true) &&
+		c.bindFieldListEq(x.Params, y.Params, false) &&
+		c.bindFieldListEq(x.Results, y.Results, false)
+}
+
+// recvEq compares method receivers and binds their names,
+// including the receiver type parameters.
+func (c *comparer) recvEq(x, y *ast.FieldList) bool {
+	if x == nil || y == nil {
+		return x == y
+	}
+	if len(x.List) != len(y.List) {
+		return false
+	}
+	for i := range x.List {
+		xf, yf := x.List[i], y.List[i]
+		if xf == nil || yf == nil {
+			if xf != yf {
+				return false
+			}
+			continue
+		}
+		if !c.recvTypeEq(xf.Type, yf.Type) || len(xf.Names) != len(yf.Names) {
+			return false
+		}
+		for j := range xf.Names {
+			if !c.bind(xf.Names[j], yf.Names[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// recvTypeEq compares receiver types, like T, *T or *T[K, V].
+// Receiver type parameters are bound.
+func (c *comparer) recvTypeEq(x, y ast.Expr) bool {
+	if c.ignoreParens {
+		x, y = unparen(x), unparen(y)
+	}
+	switch x := x.(type) {
+	case *ast.StarExpr:
+		y, ok := y.(*ast.StarExpr)
+		return ok && x != nil && y != nil && c.recvTypeEq(x.X, y.X)
+	case *ast.ParenExpr:
+		y, ok := y.(*ast.ParenExpr)
+		return ok && x != nil && y != nil && c.recvTypeEq(x.X, y.X)
+	case *ast.IndexExpr:
+		y, ok := y.(*ast.IndexExpr)
+		return ok && x != nil && y != nil &&
+			c.astExprEq(x.X, y.X) &&
+			c.recvTypeParamEq(x.Index, y.Index)
+	case *ast.IndexListExpr:
+		y, ok := y.(*ast.IndexListExpr)
+		if !ok || x == nil || y == nil || !c.astExprEq(x.X, y.X) || len(x.Indices) != len(y.Indices) {
+			return false
+		}
+		for i := range x.Indices {
+			if !c.recvTypeParamEq(x.Indices[i], y.Indices[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return c.astExprEq(x, y)
+	}
+}
+
+func (c *comparer) recvTypeParamEq(x, y ast.Expr) bool {
+	xid, ok1 := x.(*ast.Ident)
+	yid, ok2 := y.(*ast.Ident)
+	if !ok1 || !ok2 {
+		return c.astExprEq(x, y)
+	}
+	return c.bind(xid, yid)
+}
+
+// Alpha-equivalence versions of the scope-related comparisons.
+
+func (c *comparer) alphaFuncLitEq(x, y *ast.FuncLit) bool {
+	s, labels := c.enterFunc()
+	defer c.leaveFunc(s, labels)
+	return c.signatureEq(x.Type, y.Type) &&
+		c.astBlockStmtEq(x.Body, y.Body)
+}
+
+func (c *comparer) alphaFuncTypeEq(x, y *ast.FuncType) bool {
+	s := c.enterScope()
+	defer c.leaveScope(s)
+	return c.signatureEq(x, y)
+}
+
+func (c *comparer) alphaFuncDeclEq(x, y *ast.FuncDecl) bool {
+	s, labels := c.enterFunc()
+	defer c.leaveFunc(s, labels)
+	return c.recvEq(x.Recv, y.Recv) &&
+		c.nameEq(x.Name, y.Name) &&
+		c.signatureEq(x.Type, y.Type) &&
+		c.astBlockStmtEq(x.Body, y.Body) &&
+		c.commentsEq(x.Doc, y.Doc)
+}
+
+func (c *comparer) alphaAssignStmtEq(x, y *ast.AssignStmt) bool {
+	if x.Tok != token.DEFINE {
+		return x.Tok == y.Tok &&
+			c.astExprSliceEq(x.Lhs, y.Lhs) &&
+			c.astExprSliceEq(x.Rhs, y.Rhs)
+	}
+	// The right side is evaluated before the new variables are declared.
+	return x.Tok == y.Tok &&
+		c.astExprSliceEq(x.Rhs, y.Rhs) &&
+		c.defineSliceEq(x.Lhs, y.Lhs)
+}
+
+func (c *comparer) alphaRangeStmtEq(x, y *ast.RangeStmt) bool {
+	s := c.enterScope()
+	defer c.leaveScope(s)
+	if x.Tok != token.DEFINE {
+		return x.Tok == y.Tok &&
+			c.astExprEq(x.Key, y.Key) &&
+			c.astExprEq(x.Value, y.Value) &&
+			c.astExprEq(x.X, y.X) &&
+			c.astBlockStmtEq(x.Body, y.Body)
+	}
+	return x.Tok == y.Tok &&
+		c.astExprEq(x.X, y.X) &&
+		c.defineEq(x.Key, y.Key) &&
+		c.defineEq(x.Value, y.Value) &&
+		c.astBlockStmtEq(x.Body, y.Body)
+}
+
+// alphaDeclStmtEq compares local declarations and binds declared names.
+func (c *comparer) alphaDeclStmtEq(x, y *ast.DeclStmt) bool {
+	xdecl, ok1 := x.Decl.(*ast.GenDecl)
+	ydecl, ok2 := y.Decl.(*ast.GenDecl)
+	if !ok1 || !ok2 || xdecl == nil || ydecl == nil {
+		return c.astDeclEq(x.Decl, y.Decl)
+	}
+	if xdecl.Tok != ydecl.Tok || len(xdecl.Specs) != len(ydecl.Specs) ||
+		!c.commentsEq(xdecl.Doc, ydecl.Doc) {
+		return false
+	}
+	for i := range xdecl.Specs {
+		if !c.localSpecEq(xdecl.Specs[i], ydecl.Specs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *comparer) localSpecEq(x, y ast.Spec) bool {
+	switch x := x.(type) {
+	case *ast.ValueSpec:
+		y, ok := y.(*ast.ValueSpec)
+		if !ok || x == nil || y == nil || len(x.Names) != len(y.Names) {
+			return ok && x == y
+		}
+		if !c.astExprEq(x.Type, y.Type) || !c.astExprSliceEq(x.Values, y.Values) {
+			return false
+		}
+		for i := range x.Names {
+			if !c.bind(x.Names[i], y.Names[i]) {
+				return false
+			}
+		}
+		return c.commentsEq(x.Doc, y.Doc) && c.commentsEq(x.Comment, y.Comment)
+
+	case *ast.TypeSpec:
+		y, ok := y.(*ast.TypeSpec)
+		if !ok || x == nil || y == nil {
+			return ok && x == y
+		}
+		// Local types may refer to themselves.
+		if !c.bind(x.Name, y.Name) {
+			return false
+		}
+		s := c.enterScope()
+		defer c.leaveScope(s)
+		return c.bindFieldListEq(forTypeSpec(x), forTypeSpec(y), true) &&
+			c.astExprEq(x.Type, y.Type) &&
+			c.commentsEq(x.Doc, y.Doc) &&
+			c.commentsEq(x.Comment, y.Comment)
+
+	default:
+		return c.astSpecEq(x, y)
+	}
+}
+
+// Alpha-equivalence versions of the explanations.
+//
+// They bind names the same way the comparisons do,
+// so the subnodes are explained in their binding context.
+
+// explainBound is like explainFields, but maintains the bindings.
+func (c *comparer) explainBound(x, y ast.Node) *Mismatch {
+	if isNilNode(x) || isNilNode(y) || reflect.TypeOf(x) != reflect.TypeOf(y) {
+		return c.explainFields(x, y)
+	}
+
+	switch x := x.(type) {
+	case *ast.Ident:
+		y := y.(*ast.Ident)
+		if eq, ok := c.boundEq(x, y); ok && !eq {
+			return c.mismatch(x, y, NameMismatch)
+		}
+
+	case *ast.FuncLit:
+		y := y.(*ast.FuncLit)
+		defer c.leaveFunc(c.enterFunc())
+		if m := c.explainSignature(x.Type, y.Type); m != nil {
+			m.prefix(".Type")
+			return m
+		}
+		return c.explainEdges(x, y,
+			edge("Body", x.Body, y.Body))
+
+	case *ast.FuncDecl:
+		y := y.(*ast.FuncDecl)
+		defer c.leaveFunc(c.enterFunc())
+		if !c.recvEq(x.Recv, y.Recv) {
+			return c.explainEdges(x, y,
+				edge("Recv", x.Recv, y.Recv))
+		}
+		if !c.nameEq(x.Name, y.Name) {
+			m := c.mismatch(x.Name, y.Name, NameMismatch)
+			m.Path = ".Name"
+			return m
+		}
+		if m := c.explainSignature(x.Type, y.Type); m != nil {
+			m.prefix(".Type")
+			return m
+		}
+		return c.explainEdges(x, y,
+			edge("Body", x.Body, y.Body),
+			c.commentsEdge("Doc", x.Doc, y.Doc))
+
+	case *ast.FuncType:
+		y := y.(*ast.FuncType)
+		defer c.leaveScope(c.enterScope())
+		return c.explainSignature(x, y)
+
+	case *ast.AssignStmt:
+		y := y.(*ast.AssignStmt)
+		if x.Tok != token.DEFINE || y.Tok != token.DEFINE || len(x.Lhs) != len(y.Lhs) {
+			break
+		}
+		if m := c.explainEdges(x, y,
+			listEdge("Rhs", exprNodes(x.Rhs), exprNodes(y.Rhs))); m != nil {
+			return m
+		}
+		for i := range x.Lhs {
+			if m := c.explainDefine(fmt.Sprintf(".Lhs[%d]", i), x.Lhs[i], y.Lhs[i]); m != nil {
+				return m
+			}
+		}
+		return nil
+
+	case *ast.RangeStmt:
+		y := y.(*ast.RangeStmt)
+		defer c.leaveScope(c.enterScope())
+		if x.Tok != token.DEFINE || y.Tok != token.DEFINE {
+			break
+		}
+		if m := c.explainEdges(x, y,
+			edge("X", x.X, y.X)); m != nil {
+			return m
+		}
+		if m := c.explainDefine(".Key", x.Key, y.Key); m != nil {
+			return m
+		}
+		if m := c.explainDefine(".Value", x.Value, y.Value); m != nil {
+			return m
+		}
+		return c.explainEdges(x, y,
+			edge("Body", x.Body, y.Body))
+
+	case *ast.DeclStmt:
+		y := y.(*ast.DeclStmt)
+		xdecl, ok1 := x.Decl.(*ast.GenDecl)
+		ydecl, ok2 := y.Decl.(*ast.GenDecl)
+		if !ok1 || !ok2 || xdecl == nil || ydecl == nil ||
+			xdecl.Tok != ydecl.Tok || len(xdecl.Specs) != len(ydecl.Specs) {
+			break
+		}
+		for i := range xdecl.Specs {
+			if m := c.explainLocalSpec(xdecl.Specs[i], ydecl.Specs[i]); m != nil {
+				m.prefix(fmt.Sprintf(".Decl.Specs[%d]", i))
+				return m
+			}
+		}
+		return c.explainEdges(x, y,
+			c.commentsEdge("Decl.Doc", xdecl.Doc, ydecl.Doc))
+
+	case *ast.LabeledStmt:
+		y := y.(*ast.LabeledStmt)
+		if !c.labelEq(x.Label, y.Label) {
+			return c.explainEdges(x, y,
+				edge("Label", x.Label, y.Label))
+		}
+		return c.explainEdges(x, y,
+			edge("Stmt", x.Stmt, y.Stmt))
+
+	case *ast.BranchStmt:
+		y := y.(*ast.BranchStmt)
+		if x.Tok == y.Tok && c.labelEq(x.Label, y.Label) {
+			return nil
+		}
+
+	case *ast.BlockStmt, *ast.IfStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt,
+		*ast.CaseClause, *ast.CommClause, *ast.ForStmt:
+		defer c.leaveScope(c.enterScope())
+	}
+
+	return c.explainFields(x, y)
+}
+
+// explainSignature explains x and y function types differences
+// and binds their parameters, see signatureEq.
+func (c *comparer) explainSignature(x, y *ast.FuncType) *Mismatch {
+	if x == nil || y == nil {
+		if x == y {
+			return nil
+		}
+		return c.explainFields(x, y)
+	}
+	if m := c.explainParams("TypeParams", forFuncType(x), forFuncType(y), true); m != nil {
+		return m
+	}
+	if m := c.explainParams("Params", x.Params, y.Params, false); m != nil {
+		return m
+	}
+	return c.explainParams("Results", x.Results, y.Results, false)
+}
+
+// explainParams explains x and y parameters lists differences
+// and binds their names, see bindFieldListEq.
+func (c *comparer) explainParams(name string, x, y *ast.FieldList, typeParams bool) *Mismatch {
+	if x == nil || y == nil {
+		if x == y {
+			return nil
+		}
+		m := c.explainFields(x, y)
+		m.prefix("." + name)
+		return m
+	}
+	if len(x.List) != len(y.List) {
+		m := c.mismatch(x, y, LengthMismatch)
+		m.Path = "." + name + ".List"
+		return m
+	}
+
+	for i := range x.List {
+		xf, yf := x.List[i], y.List[i]
+		path := fmt.Sprintf(".%s.List[%d]", name, i)
+		if xf == nil || yf == nil || len(xf.Names) != len(yf.Names) {
+			if xf == yf {
+				continue
+			}
+			m := c.explainFields(xf, yf)
+			m.prefix(path)
+			return m
+		}
+		if !typeParams && !c.astExprEq(xf.Type, yf.Type) {
+			m := c.explainNode(xf.Type, yf.Type)
+			m.prefix(path + ".Type")
+			return m
+		}
+		for j := range xf.Names {
+			if !c.bind(xf.Names[j], yf.Names[j]) {
+				m := c.mismatch(xf.Names[j], yf.Names[j], NameMismatch)
+				m.Path = fmt.Sprintf("%s.Names[%d]", path, j)
+				return m
+			}
+		}
+		if typeParams && !c.astExprEq(xf.Type, yf.Type) {
+			m := c.explainNode(xf.Type, yf.Type)
+			m.prefix(path + ".Type")
+			return m
+		}
+		if !c.tagsEq(xf.Tag, yf.Tag) ||
+			!c.commentsEq(xf.Doc, yf.Doc) ||
+			!c.commentsEq(xf.Comment, yf.Comment) {
+			// Names are bound, so only the tag or comments differ.
+			m := c.explainNode(xf, yf)
+			m.prefix(path)
+			return m
+		}
+	}
+	return nil
+}
+
+// explainDefine explains x and y expressions on the left side of :=,
+// see defineEq.
+func (c *comparer) explainDefine(path string, x, y ast.Expr) *Mismatch {
+	if c.defineEq(x, y) {
+		return nil
+	}
+	m := c.explainNode(x, y)
+	m.prefix(path)
+	return m
+}
+
+// explainLocalSpec explains x and y local declaration specs differences,
+// see localSpecEq. It returns nil if they are equal.
+func (c *comparer) explainLocalSpec(x, y ast.Spec) *Mismatch {
+	if x, ok := x.(*ast.ValueSpec); ok {
+		y, ok := y.(*ast.ValueSpec)
+		if ok && x != nil && y != nil && len(x.Names) == len(y.Names) {
+			// The values are evaluated before the names are declared.
+			if m := c.explainEdges(x, y,
+				edge("Type", x.Type, y.Type),
+				listEdge("Values", exprNodes(x.Values), exprNodes(y.Values))); m != nil {
+				return m
+			}
+			for i := range x.Names {
+				if !c.bind(x.Names[i], y.Names[i]) {
+					m := c.mismatch(x.Names[i], y.Names[i], NameMismatch)
+					m.Path = fmt.Sprintf(".Names[%d]", i)
+					return m
+				}
+			}
+			return c.explainEdges(x, y,
+				c.commentsEdge("Doc", x.Doc, y.Doc),
+				c.commentsEdge("Comment", x.Comment, y.Comment))
+		}
+	}
+	if c.localSpecEq(x, y) {
+		return nil
+	}
+	return c.explainNode(x, y)
+}
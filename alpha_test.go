@@ -0,0 +1,168 @@
+package astequal
+
+import (
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestAlphaEquivalence(t *testing.T) {
+	tests := []struct {
+		x     string
+		y     string
+		equal bool
+	}{
+		{`func(a int) int { return a+1 }`, `func(b int) int { return b+1 }`, true},
+		{`func(a int) int { return a+1 }`, `func(b int) int { return a+1 }`, false},
+		{`func(a, b int) int { return a-b }`, `func(b, a int) int { return b-a }`, true},
+		{`func(a, b int) int { return a-b }`, `func(b, a int) int { return a-b }`, false},
+		{`func(a int) (r int) { r = a; return }`, `func(b int) (s int) { s = b; return }`, true},
+		{`func(a int) (r int) { r = a; return }`, `func(b int) (s int) { b = s; return }`, false},
+		{`func() { x := f(); g(x) }`, `func() { y := f(); g(y) }`, true},
+		{`func() { x := f(); g(x) }`, `func() { y := f(); g(x) }`, false},
+		{`func(x int) { x := x + 1; g(x) }`, `func(y int) { z := y + 1; g(z) }`, true},
+		{`func(x int) { x := x + 1; g(x) }`, `func(y int) { z := z + 1; g(z) }`, false},
+		{`func(x int) { { x := 1; g(x) }; g(x) }`, `func(y int) { { z := 1; g(z) }; g(y) }`, true},
+		{`func(x int) { { x := 1; g(x) }; g(x) }`, `func(y int) { { z := 1; g(z) }; g(z) }`, false},
+		{`func() { a, b := f(); a, c := g(); h(a, b, c) }`, `func() { x, y := f(); x, z := g(); h(x, y, z) }`, true},
+		{`func() { for i, v := range xs { f(i, v) } }`, `func() { for j, w := range xs { f(j, w) } }`, true},
+		{`func() { for i, v := range xs { f(i, v) } }`, `func() { for j, w := range xs { f(w, j) } }`, false},
+		{`func() { for i := 0; i < n; i++ { f(i) } }`, `func() { for j := 0; j < n; j++ { f(j) } }`, true},
+		{`func() { if v, ok := m[k]; ok { f(v) } }`, `func() { if w, found := m[k]; found { f(w) } }`, true},
+		{`func() { switch v := x.(type) { case int: f(v) } }`, `func() { switch w := x.(type) { case int: f(w) } }`, true},
+		{`func() { select { case v := <-ch: f(v) } }`, `func() { select { case w := <-ch: f(w) } }`, true},
+		{`func() { var a, b int; f(a, b) }`, `func() { var c, d int; f(c, d) }`, true},
+		{`func() { const a = 1; f(a) }`, `func() { const c = 1; f(c) }`, true},
+		{`func() { type T struct{ x int }; var t T; f(t.x) }`, `func() { type U struct{ x int }; var u U; f(u.x) }`, true},
+		{`func() { type T struct{ x int }; var t T; f(t.x) }`, `func() { type U struct{ y int }; var u U; f(u.y) }`, false},
+		{`func() { L: for { break L } }`, `func() { M: for { break M } }`, true},
+		{`func() { L: for { break L } }`, `func() { M: for { break L } }`, false},
+		{`func() { goto L; L: f() }`, `func() { goto M; M: f() }`, true},
+		{`func(a int) { f(T{a: a}) }`, `func(b int) { f(T{a: b}) }`, true},
+		{`func(a int) { f(map[int]int{a: 1}) }`, `func(b int) { f(map[int]int{b: 1}) }`, true},
+		{`func(a int) { f(a.x) }`, `func(b int) { f(b.x) }`, true},
+		{`func(a int) { f(a.x) }`, `func(b int) { f(b.y) }`, false},
+		{`func(_ int) {}`, `func(_ int) {}`, true},
+		{`func(_ int) {}`, `func(a int) {}`, false},
+		{`func(a int) { f(g) }`, `func(b int) { f(h) }`, false},
+		{`func(g int) { f(g) }`, `func(b int) { f(g) }`, false},
+		{`func(a int) { f(func(a int) { g(a) }) }`, `func(b int) { f(func(c int) { g(c) }) }`, true},
+		{`func(a int) { f(func(c int) { g(a) }) }`, `func(b int) { f(func(c int) { g(c) }) }`, false},
+		{`func(a int) { _ = func() int { return a } }`, `func(b int) { _ = func() int { return b } }`, true},
+		{`func(a int)`, `func(b int)`, true},
+		{`func() { x, _ := f(); g(x) }`, `func() { y, _ := f(); g(y) }`, true},
+		{`func() { x, _ := f(); g(x) }`, `func() { y, z := f(); g(y) }`, false},
+	}
+
+	cmp := New(AlphaEquivalence())
+	for _, test := range tests {
+		x := strparse.Expr(test.x)
+		y := strparse.Expr(test.y)
+		if have := cmp.Expr(x, y); have != test.equal {
+			t.Errorf("Expr:\nx: %s\ny: %s\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.equal)
+		}
+		if have := cmp.Expr(x, x); !have {
+			t.Errorf("Expr:\nx: %s\nnot equal to itself", test.x)
+		}
+	}
+}
+
+func TestAlphaEquivalenceDecl(t *testing.T) {
+	tests := []struct {
+		x     string
+		y     string
+		equal bool
+	}{
+		{`func f(a int) int { return a }`, `func f(b int) int { return b }`, true},
+		{`func f(a int) int { return a }`, `func g(b int) int { return b }`, false},
+		{`func (r T) f() int { return r.x }`, `func (t T) f() int { return t.x }`, true},
+		{`func (r T) f() int { return r.x }`, `func (t U) f() int { return t.x }`, false},
+		{`func (r *T[K]) f(k K) { r.m[k] = 1 }`, `func (s *T[X]) f(y X) { s.m[y] = 1 }`, true},
+		{`func (r *T[K, V]) f(v V) {}`, `func (s *T[A, B]) f(v B) {}`, true},
+		{`func (r *T[K, V]) f(v V) {}`, `func (s *T[A, B]) f(v A) {}`, false},
+		{`func f[T any](x T) T { return x }`, `func f[U any](y U) U { return y }`, true},
+		{`func f[T any, S ~[]T](x S) {}`, `func f[U any, V ~[]U](y V) {}`, true},
+		{`func f[T any, S ~[]T](x S) {}`, `func f[U any, V ~[]V](y V) {}`, false},
+		{`var x = y`, `var z = y`, false},
+	}
+
+	cmp := New(AlphaEquivalence())
+	for _, test := range tests {
+		x := strparse.Decl(test.x)
+		y := strparse.Decl(test.y)
+		if have := cmp.Decl(x, y); have != test.equal {
+			t.Errorf("Decl:\nx: %s\ny: %s\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.equal)
+		}
+	}
+}
+
+func TestAlphaEquivalenceExplain(t *testing.T) {
+	tests := []struct {
+		x    string
+		y    string
+		want string
+	}{
+		{
+			`func(a int) { g(a) }`,
+			`func(b int) { g(a) }`,
+			`FuncLit.Body.List[0].X.Args[0]: name mismatch`,
+		},
+		{
+			`func(a, b int) { g(a, b) }`,
+			`func(b, a int) { g(b, b) }`,
+			`FuncLit.Body.List[0].X.Args[1]: name mismatch`,
+		},
+		{
+			`func(a int) { x := a; g(x, 1) }`,
+			`func(b int) { y := b; g(y, 2) }`,
+			`FuncLit.Body.List[1].X.Args[1]: value mismatch`,
+		},
+		{
+			`func(a int) { var x = a; g(x) }`,
+			`func(b int) { var y = b; g(b) }`,
+			`FuncLit.Body.List[1].X.Args[0]: name mismatch`,
+		},
+		{
+			`func(a int) int { return a }`,
+			`func(b int) string { return b }`,
+			`FuncLit.Type.Results.List[0].Type: name mismatch`,
+		},
+		{
+			`func(a []int) { for i := range a { g(i, a) } }`,
+			`func(b []int) { for j := range b { g(j, 0) } }`,
+			`FuncLit.Body.List[0].Body.List[0].X.Args[1]: kind mismatch`,
+		},
+		{
+			`func() { L: for { break L }; g(1) }`,
+			`func() { M: for { break M }; g(2) }`,
+			`FuncLit.Body.List[1].X.Args[0]: value mismatch`,
+		},
+	}
+
+	cmp := New(AlphaEquivalence())
+	for _, test := range tests {
+		x := strparse.Expr(test.x)
+		y := strparse.Expr(test.y)
+		if cmp.Expr(x, y) {
+			t.Errorf("Expr:\nx: %s\ny: %s\nhave: true\nwant: false", test.x, test.y)
+			continue
+		}
+		if m := cmp.Explain(x, y); m == nil || m.String() != test.want {
+			t.Errorf("Explain:\nx: %s\ny: %s\nhave: %v\nwant: %s",
+				test.x, test.y, m, test.want)
+		}
+		if ms := cmp.ExplainAll(x, y); len(ms) == 0 || ms[0].String() != test.want {
+			t.Errorf("ExplainAll:\nx: %s\ny: %s\nhave: %v\nwant: [%s ...]",
+				test.x, test.y, ms, test.want)
+		}
+	}
+
+	x := strparse.Decl(`func (r T) f(a int) { r.g(a) }`)
+	y := strparse.Decl(`func (s T) f(b int) { s.g(a) }`)
+	want := `FuncDecl.Body.List[0].X.Args[0]: name mismatch`
+	if m := cmp.Explain(x, y); m == nil || m.String() != want {
+		t.Errorf("Explain decl:\nhave: %v\nwant: %s", m, want)
+	}
+}
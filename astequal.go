@@ -3,6 +3,7 @@ package astequal
 
 import (
 	"go/ast"
+	"go/build/constraint"
 	"go/token"
 )
 
@@ -10,9 +11,10 @@ import (
 //
 // Nil arguments are permitted: true is returned if x and y are both nils.
 //
-// See also: Expr, Stmt, Decl functions.
+// See also: Expr, Stmt, Decl, Spec, File functions.
 func Node(x, y ast.Node) bool {
-	return astNodeEq(x, y)
+	var c comparer
+	return c.astNodeEq(x, y)
 }
 
 // Expr reports whether two AST expressions are structurally (deep) equal.
@@ -20,7 +22,8 @@ func Node(x, y ast.Node) bool {
 // Nil arguments are permitted: true is returned if x and y are both nils.
 // ast.BadExpr comparison always yields false.
 func Expr(x, y ast.Expr) bool {
-	return astExprEq(x, y)
+	var c comparer
+	return c.astExprEq(x, y)
 }
 
 // Stmt reports whether two AST statements are structurally (deep) equal.
@@ -28,7 +31,8 @@ func Expr(x, y ast.Expr) bool {
 // Nil arguments are permitted: true is returned if x and y are both nils.
 // ast.BadStmt comparison always yields false.
 func Stmt(x, y ast.Stmt) bool {
-	return astStmtEq(x, y)
+	var c comparer
+	return c.astStmtEq(x, y)
 }
 
 // Decl reports whether two AST declarations are structurally (deep) equal.
@@ -36,11 +40,97 @@ func Stmt(x, y ast.Stmt) bool {
 // Nil arguments are permitted: true is returned if x and y are both nils.
 // ast.BadDecl comparison always yields false.
 func Decl(x, y ast.Decl) bool {
-	return astDeclEq(x, y)
+	var c comparer
+	return c.astDeclEq(x, y)
+}
+
+// Spec reports whether two AST specs are structurally (deep) equal.
+//
+// Nil arguments are permitted: true is returned if x and y are both nils.
+func Spec(x, y ast.Spec) bool {
+	var c comparer
+	return c.astSpecEq(x, y)
+}
+
+// File reports whether two AST files are structurally (deep) equal.
+//
+// Files are equal if they have the same package name, the same
+// build constraints and pairwise equal declarations.
+// Build constraints (and the Go version derived from them)
+// are only available if files were parsed with parser.ParseComments.
+//
+// Nil arguments are permitted: true is returned if x and y are both nils.
+func File(x, y *ast.File) bool {
+	var c comparer
+	return c.astFileEq(x, y)
 }
 
 // Functions to perform deep equallity checks between arbitrary AST nodes.
 
+// comparer holds the comparison settings.
+//
+// A zero comparer implements the default (package-level functions) behavior.
+type comparer struct {
+	config
+
+	// hooked enables the per-node checks of the modes that can
+	// replace a node comparison: pattern matching, parentheses skipping,
+	// custom comparison, alpha-equivalence, types and literal values.
+	// Without them, the comparison takes the default fast path.
+	hooked bool
+
+	// Alpha-equivalence mode state.
+	bindings   []binding
+	labels     []binding
+	scopeStart int
+	plainNames bool
+
+	// Types mode state for different x and y infos:
+	// the compared roots positions and the local objects
+	// declared by the compared nodes, see localEq.
+	xroot   token.Pos
+	yroot   token.Pos
+	objects []objectPair
+
+	// Pattern matching state, see Match.
+	match *matcher
+
+	// explainAll makes explainEdges report all differences.
+	explainAll bool
+}
+
+// state is a saved comparer bindings state.
+//
+// A failed comparison attempt may leave some bindings behind,
+// so the state is restored before another attempt is made.
+type state struct {
+	bindings int
+	labels   int
+	objects  int
+	binds    int
+}
+
+func (c *comparer) save() state {
+	s := state{
+		bindings: len(c.bindings),
+		labels:   len(c.labels),
+		objects:  len(c.objects),
+	}
+	if c.match != nil {
+		s.binds = len(c.match.names)
+	}
+	return s
+}
+
+func (c *comparer) restore(s state) {
+	c.bindings = c.bindings[:s.bindings]
+	c.labels = c.labels[:s.labels]
+	c.objects = c.objects[:s.objects]
+	if c.match != nil {
+		c.match.unbind(s.binds)
+	}
+}
+
 // Compare interface node types.
 //
 // Interfaces, as well as their values, can be nil.
@@ -49,233 +139,315 @@ func Decl(x, y ast.Decl) bool {
 // nil checks are required as nodes can be constructed
 // manually, or be partially invalid/incomplete.
 
-func astNodeEq(x, y ast.Node) bool {
+func (c *comparer) astNodeEq(x, y ast.Node) bool {
+	if x == nil || y == nil {
+		return x == y
+	}
+
 	switch x := x.(type) {
 	case ast.Expr:
 		y, ok := y.(ast.Expr)
-		return ok && astExprEq(x, y)
+		return ok && c.astExprEq(x, y)
 	case ast.Stmt:
 		y, ok := y.(ast.Stmt)
-		return ok && astStmtEq(x, y)
+		return ok && c.astStmtEq(x, y)
 	case ast.Decl:
 		y, ok := y.(ast.Decl)
-		return ok && astDeclEq(x, y)
+		return ok && c.astDeclEq(x, y)
+	case ast.Spec:
+		y, ok := y.(ast.Spec)
+		return ok && c.astSpecEq(x, y)
 
 	case *ast.Field:
 		y, ok := y.(*ast.Field)
-		return ok && astFieldEq(x, y)
+		return ok && c.astFieldEq(x, y)
 	case *ast.FieldList:
 		y, ok := y.(*ast.FieldList)
-		return ok && astFieldListEq(x, y)
+		return ok && c.astFieldListEq(x, y)
+
+	case *ast.File:
+		y, ok := y.(*ast.File)
+		return ok && c.astFileEq(x, y)
+
+	case *ast.CommentGroup:
+		y, ok := y.(*ast.CommentGroup)
+		return ok && c.astCommentGroupEq(x, y)
+	case *ast.Comment:
+		y, ok := y.(*ast.Comment)
+		return ok && c.astCommentEq(x, y)
 
 	default:
 		return false
 	}
 }
 
-func astExprEq(x, y ast.Expr) bool {
+func (c *comparer) astExprEq(x, y ast.Expr) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
+	if c.hooked {
+		if c.match != nil {
+			if name, ok := metavar(x); ok {
+				return c.match.bind(name, y)
+			}
+		}
+		if c.ignoreParens {
+			x, y = unparen(x), unparen(y)
+		}
+		if c.equalFunc != nil && !isLeaf(x) {
+			if eq, ok := c.equalFunc(x, y); ok {
+				return eq
+			}
+		}
+	}
 
 	switch x := x.(type) {
 	case *ast.Ident:
-		y, ok := y.(*ast.Ident)
-		return ok && astIdentEq(x, y)
+		if y, ok := y.(*ast.Ident); ok {
+			return c.astIdentEq(x, y)
+		}
+		return c.xinfo != nil && c.qualifiedEq(x, y)
 
 	case *ast.BasicLit:
 		y, ok := y.(*ast.BasicLit)
-		return ok && astBasicLitEq(x, y)
+		return ok && c.astBasicLitEq(x, y)
 
 	case *ast.FuncLit:
 		y, ok := y.(*ast.FuncLit)
-		return ok && astFuncLitEq(x, y)
+		return ok && c.astFuncLitEq(x, y)
 
 	case *ast.CompositeLit:
 		y, ok := y.(*ast.CompositeLit)
-		return ok && astCompositeLitEq(x, y)
+		return ok && c.astCompositeLitEq(x, y)
 
 	case *ast.ParenExpr:
 		y, ok := y.(*ast.ParenExpr)
-		return ok && astParenExprEq(x, y)
+		return ok && c.astParenExprEq(x, y)
 
 	case *ast.SelectorExpr:
-		y, ok := y.(*ast.SelectorExpr)
-		return ok && astSelectorExprEq(x, y)
+		if y, ok := y.(*ast.SelectorExpr); ok {
+			return c.astSelectorExprEq(x, y)
+		}
+		return c.xinfo != nil && c.qualifiedEq(x, y)
 
 	case *ast.IndexExpr:
 		y, ok := y.(*ast.IndexExpr)
-		return ok && astIndexExprEq(x, y)
+		return ok && c.astIndexExprEq(x, y)
 
 	case *ast.IndexListExpr:
 		y, ok := y.(*ast.IndexListExpr)
-		return ok && astIndexListExprEq(x, y)
+		return ok && c.astIndexListExprEq(x, y)
 
 	case *ast.SliceExpr:
 		y, ok := y.(*ast.SliceExpr)
-		return ok && astSliceExprEq(x, y)
+		return ok && c.astSliceExprEq(x, y)
 
 	case *ast.TypeAssertExpr:
 		y, ok := y.(*ast.TypeAssertExpr)
-		return ok && astTypeAssertExprEq(x, y)
+		return ok && c.astTypeAssertExprEq(x, y)
 
 	case *ast.CallExpr:
 		y, ok := y.(*ast.CallExpr)
-		return ok && astCallExprEq(x, y)
+		return ok && c.astCallExprEq(x, y)
 
 	case *ast.StarExpr:
 		y, ok := y.(*ast.StarExpr)
-		return ok && astStarExprEq(x, y)
+		return ok && c.astStarExprEq(x, y)
 
 	case *ast.UnaryExpr:
 		y, ok := y.(*ast.UnaryExpr)
-		return ok && astUnaryExprEq(x, y)
+		return ok && c.astUnaryExprEq(x, y)
 
 	case *ast.BinaryExpr:
 		y, ok := y.(*ast.BinaryExpr)
-		return ok && astBinaryExprEq(x, y)
+		return ok && c.astBinaryExprEq(x, y)
 
 	case *ast.KeyValueExpr:
 		y, ok := y.(*ast.KeyValueExpr)
-		return ok && astKeyValueExprEq(x, y)
+		return ok && c.astKeyValueExprEq(x, y)
 
 	case *ast.ArrayType:
 		y, ok := y.(*ast.ArrayType)
-		return ok && astArrayTypeEq(x, y)
+		return ok && c.astArrayTypeEq(x, y)
 
 	case *ast.StructType:
 		y, ok := y.(*ast.StructType)
-		return ok && astStructTypeEq(x, y)
+		return ok && c.astStructTypeEq(x, y)
 
 	case *ast.FuncType:
 		y, ok := y.(*ast.FuncType)
-		return ok && astFuncTypeEq(x, y)
+		return ok && c.astFuncTypeEq(x, y)
 
 	case *ast.InterfaceType:
 		y, ok := y.(*ast.InterfaceType)
-		return ok && astInterfaceTypeEq(x, y)
+		return ok && c.astInterfaceTypeEq(x, y)
 
 	case *ast.MapType:
 		y, ok := y.(*ast.MapType)
-		return ok && astMapTypeEq(x, y)
+		return ok && c.astMapTypeEq(x, y)
 
 	case *ast.ChanType:
 		y, ok := y.(*ast.ChanType)
-		return ok && astChanTypeEq(x, y)
+		return ok && c.astChanTypeEq(x, y)
 
 	case *ast.Ellipsis:
 		y, ok := y.(*ast.Ellipsis)
-		return ok && astEllipsisEq(x, y)
+		return ok && c.astEllipsisEq(x, y)
 
 	default:
 		return false
 	}
 }
 
-func astStmtEq(x, y ast.Stmt) bool {
+func (c *comparer) astStmtEq(x, y ast.Stmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
+	if c.hooked {
+		if c.match != nil {
+			if eq, ok := c.match.stmtEq(x, y); ok {
+				return eq
+			}
+		}
+		if c.equalFunc != nil {
+			if eq, ok := c.equalFunc(x, y); ok {
+				return eq
+			}
+		}
+	}
 
 	switch x := x.(type) {
 	case *ast.ExprStmt:
 		y, ok := y.(*ast.ExprStmt)
-		return ok && astExprStmtEq(x, y)
+		return ok && c.astExprStmtEq(x, y)
 
 	case *ast.SendStmt:
 		y, ok := y.(*ast.SendStmt)
-		return ok && astSendStmtEq(x, y)
+		return ok && c.astSendStmtEq(x, y)
 
 	case *ast.IncDecStmt:
 		y, ok := y.(*ast.IncDecStmt)
-		return ok && astIncDecStmtEq(x, y)
+		return ok && c.astIncDecStmtEq(x, y)
 
 	case *ast.AssignStmt:
 		y, ok := y.(*ast.AssignStmt)
-		return ok && astAssignStmtEq(x, y)
+		return ok && c.astAssignStmtEq(x, y)
 
 	case *ast.GoStmt:
 		y, ok := y.(*ast.GoStmt)
-		return ok && astGoStmtEq(x, y)
+		return ok && c.astGoStmtEq(x, y)
 
 	case *ast.DeferStmt:
 		y, ok := y.(*ast.DeferStmt)
-		return ok && astDeferStmtEq(x, y)
+		return ok && c.astDeferStmtEq(x, y)
 
 	case *ast.ReturnStmt:
 		y, ok := y.(*ast.ReturnStmt)
-		return ok && astReturnStmtEq(x, y)
+		return ok && c.astReturnStmtEq(x, y)
 
 	case *ast.BranchStmt:
 		y, ok := y.(*ast.BranchStmt)
-		return ok && astBranchStmtEq(x, y)
+		return ok && c.astBranchStmtEq(x, y)
 
 	case *ast.BlockStmt:
 		y, ok := y.(*ast.BlockStmt)
-		return ok && astBlockStmtEq(x, y)
+		return ok && c.astBlockStmtEq(x, y)
 
 	case *ast.IfStmt:
 		y, ok := y.(*ast.IfStmt)
-		return ok && astIfStmtEq(x, y)
+		return ok && c.astIfStmtEq(x, y)
 
 	case *ast.CaseClause:
 		y, ok := y.(*ast.CaseClause)
-		return ok && astCaseClauseEq(x, y)
+		return ok && c.astCaseClauseEq(x, y)
 
 	case *ast.SwitchStmt:
 		y, ok := y.(*ast.SwitchStmt)
-		return ok && astSwitchStmtEq(x, y)
+		return ok && c.astSwitchStmtEq(x, y)
 
 	case *ast.TypeSwitchStmt:
 		y, ok := y.(*ast.TypeSwitchStmt)
-		return ok && astTypeSwitchStmtEq(x, y)
+		return ok && c.astTypeSwitchStmtEq(x, y)
 
 	case *ast.CommClause:
 		y, ok := y.(*ast.CommClause)
-		return ok && astCommClauseEq(x, y)
+		return ok && c.astCommClauseEq(x, y)
 
 	case *ast.SelectStmt:
 		y, ok := y.(*ast.SelectStmt)
-		return ok && astSelectStmtEq(x, y)
+		return ok && c.astSelectStmtEq(x, y)
 
 	case *ast.ForStmt:
 		y, ok := y.(*ast.ForStmt)
-		return ok && astForStmtEq(x, y)
+		return ok && c.astForStmtEq(x, y)
 
 	case *ast.RangeStmt:
 		y, ok := y.(*ast.RangeStmt)
-		return ok && astRangeStmtEq(x, y)
+		return ok && c.astRangeStmtEq(x, y)
 
 	case *ast.DeclStmt:
 		y, ok := y.(*ast.DeclStmt)
-		return ok && astDeclStmtEq(x, y)
+		return ok && c.astDeclStmtEq(x, y)
 
 	case *ast.LabeledStmt:
 		y, ok := y.(*ast.LabeledStmt)
-		return ok && astLabeledStmtEq(x, y)
+		return ok && c.astLabeledStmtEq(x, y)
 
 	case *ast.EmptyStmt:
 		y, ok := y.(*ast.EmptyStmt)
-		return ok && astEmptyStmtEq(x, y)
+		return ok && c.astEmptyStmtEq(x, y)
 
 	default:
 		return false
 	}
 }
 
-func astDeclEq(x, y ast.Decl) bool {
+func (c *comparer) astDeclEq(x, y ast.Decl) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
+	if c.hooked && c.equalFunc != nil {
+		if eq, ok := c.equalFunc(x, y); ok {
+			return eq
+		}
+	}
 
 	switch x := x.(type) {
 	case *ast.GenDecl:
 		y, ok := y.(*ast.GenDecl)
-		return ok && astGenDeclEq(x, y)
+		return ok && c.astGenDeclEq(x, y)
 
 	case *ast.FuncDecl:
 		y, ok := y.(*ast.FuncDecl)
-		return ok && astFuncDeclEq(x, y)
+		return ok && c.astFuncDeclEq(x, y)
+
+	default:
+		return false
+	}
+}
+
+func (c *comparer) astSpecEq(x, y ast.Spec) bool {
+	if x == nil || y == nil {
+		return x == y
+	}
+	if c.hooked && c.equalFunc != nil {
+		if eq, ok := c.equalFunc(x, y); ok {
+			return eq
+		}
+	}
+
+	switch x := x.(type) {
+	case *ast.ImportSpec:
+		y, ok := y.(*ast.ImportSpec)
+		return ok && c.astImportSpecEq(x, y)
+
+	case *ast.TypeSpec:
+		y, ok := y.(*ast.TypeSpec)
+		return ok && c.astTypeSpecEq(x, y)
+
+	case *ast.ValueSpec:
+		y, ok := y.(*ast.ValueSpec)
+		return ok && c.astValueSpecEq(x, y)
 
 	default:
 		return false
@@ -287,417 +459,536 @@ func astDeclEq(x, y ast.Decl) bool {
 // Any node of pointer type permitted to be nil,
 // hence nil checks are mandatory.
 
-func astIdentEq(x, y *ast.Ident) bool {
+func (c *comparer) astIdentEq(x, y *ast.Ident) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
+	if c.hooked {
+		if eq, ok := c.identHook(x, y); ok {
+			return eq
+		}
+	}
 	return x.Name == y.Name
 }
 
-func astKeyValueExprEq(x, y *ast.KeyValueExpr) bool {
+func (c *comparer) astKeyValueExprEq(x, y *ast.KeyValueExpr) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.Key, y.Key) && astExprEq(x.Value, y.Value)
+	return c.keyEq(x.Key, y.Key) && c.astExprEq(x.Value, y.Value)
 }
 
-func astArrayTypeEq(x, y *ast.ArrayType) bool {
+func (c *comparer) astArrayTypeEq(x, y *ast.ArrayType) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.Len, y.Len) && astExprEq(x.Elt, y.Elt)
+	return c.astExprEq(x.Len, y.Len) && c.astExprEq(x.Elt, y.Elt)
 }
 
-func astStructTypeEq(x, y *ast.StructType) bool {
+func (c *comparer) astStructTypeEq(x, y *ast.StructType) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astFieldListEq(x.Fields, y.Fields)
+	return c.astFieldListEq(x.Fields, y.Fields)
 }
 
-func astFuncTypeEq(x, y *ast.FuncType) bool {
+func (c *comparer) astFuncTypeEq(x, y *ast.FuncType) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astFieldListEq(x.Params, y.Params) &&
-		astFieldListEq(x.Results, y.Results) &&
-		astFieldListEq(forFuncType(x), forFuncType(y))
+	if c.alpha {
+		return c.alphaFuncTypeEq(x, y)
+	}
+	return c.astFieldListEq(x.Params, y.Params) &&
+		c.astFieldListEq(x.Results, y.Results) &&
+		c.astFieldListEq(forFuncType(x), forFuncType(y))
 }
 
-func astBasicLitEq(x, y *ast.BasicLit) bool {
+func (c *comparer) astBasicLitEq(x, y *ast.BasicLit) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
+	if c.hooked {
+		if c.equalFunc != nil {
+			if eq, ok := c.equalFunc(x, y); ok {
+				return eq
+			}
+		}
+		if c.literalValues {
+			return literalValueEq(x, y)
+		}
+	}
 	return x.Kind == y.Kind && x.Value == y.Value
 }
 
-func astBlockStmtEq(x, y *ast.BlockStmt) bool {
+func (c *comparer) astBlockStmtEq(x, y *ast.BlockStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astStmtSliceEq(x.List, y.List)
+	if c.alpha {
+		defer c.leaveScope(c.enterScope())
+	}
+	return c.astStmtSliceEq(x.List, y.List)
 }
 
-func astFieldEq(x, y *ast.Field) bool {
+func (c *comparer) astFieldEq(x, y *ast.Field) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astIdentSliceEq(x.Names, y.Names) &&
-		astExprEq(x.Type, y.Type)
+	if c.hooked && c.equalFunc != nil {
+		if eq, ok := c.equalFunc(x, y); ok {
+			return eq
+		}
+	}
+	return c.nameSliceEq(x.Names, y.Names) &&
+		c.astExprEq(x.Type, y.Type) &&
+		c.tagsEq(x.Tag, y.Tag) &&
+		c.commentsEq(x.Doc, y.Doc) &&
+		c.commentsEq(x.Comment, y.Comment)
 }
 
-func astFuncLitEq(x, y *ast.FuncLit) bool {
+func (c *comparer) astFuncLitEq(x, y *ast.FuncLit) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astFuncTypeEq(x.Type, y.Type) &&
-		astBlockStmtEq(x.Body, y.Body)
+	if c.alpha {
+		return c.alphaFuncLitEq(x, y)
+	}
+	return c.astFuncTypeEq(x.Type, y.Type) &&
+		c.astBlockStmtEq(x.Body, y.Body)
 }
 
-func astCompositeLitEq(x, y *ast.CompositeLit) bool {
+func (c *comparer) astCompositeLitEq(x, y *ast.CompositeLit) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.Type, y.Type) &&
-		astExprSliceEq(x.Elts, y.Elts)
+	if c.elideTypes {
+		return c.compositeLitEq(x, y, nil, nil)
+	}
+	if c.ignoreOrder && unorderedLit(c.xinfo, x) && unorderedLit(c.yinfo, y) {
+		return c.astExprEq(x.Type, y.Type) &&
+			c.unorderedExprSliceEq(x.Elts, y.Elts)
+	}
+	return c.astExprEq(x.Type, y.Type) &&
+		c.astExprSliceEq(x.Elts, y.Elts)
 }
 
-func astSelectorExprEq(x, y *ast.SelectorExpr) bool {
+func (c *comparer) astSelectorExprEq(x, y *ast.SelectorExpr) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.X, y.X) && astIdentEq(x.Sel, y.Sel)
+	if c.xinfo != nil {
+		if xobj := c.qualifiedObject(c.xinfo, x); xobj != nil {
+			yobj := c.qualifiedObject(c.yinfo, y)
+			return yobj != nil && c.sameObject(xobj, yobj)
+		}
+	}
+	return c.astExprEq(x.X, y.X) && c.nameEq(x.Sel, y.Sel)
 }
 
-func astIndexExprEq(x, y *ast.IndexExpr) bool {
+func (c *comparer) astIndexExprEq(x, y *ast.IndexExpr) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.X, y.X) && astExprEq(x.Index, y.Index)
+	return c.astExprEq(x.X, y.X) && c.astExprEq(x.Index, y.Index)
 }
 
-func astIndexListExprEq(x, y *ast.IndexListExpr) bool {
+func (c *comparer) astIndexListExprEq(x, y *ast.IndexListExpr) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.X, y.X) && astExprSliceEq(x.Indices, y.Indices)
+	return c.astExprEq(x.X, y.X) && c.astExprSliceEq(x.Indices, y.Indices)
 }
 
-func astSliceExprEq(x, y *ast.SliceExpr) bool {
+func (c *comparer) astSliceExprEq(x, y *ast.SliceExpr) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.X, y.X) &&
-		astExprEq(x.Low, y.Low) &&
-		astExprEq(x.High, y.High) &&
-		astExprEq(x.Max, y.Max)
+	return c.astExprEq(x.X, y.X) &&
+		c.astExprEq(x.Low, y.Low) &&
+		c.astExprEq(x.High, y.High) &&
+		c.astExprEq(x.Max, y.Max)
 }
 
-func astTypeAssertExprEq(x, y *ast.TypeAssertExpr) bool {
+func (c *comparer) astTypeAssertExprEq(x, y *ast.TypeAssertExpr) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.X, y.X) && astExprEq(x.Type, y.Type)
+	return c.astExprEq(x.X, y.X) && c.astExprEq(x.Type, y.Type)
 }
 
-func astInterfaceTypeEq(x, y *ast.InterfaceType) bool {
+func (c *comparer) astInterfaceTypeEq(x, y *ast.InterfaceType) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astFieldListEq(x.Methods, y.Methods)
+	if c.ignoreOrder && x.Methods != nil && y.Methods != nil {
+		return c.unorderedFieldSliceEq(x.Methods.List, y.Methods.List)
+	}
+	return c.astFieldListEq(x.Methods, y.Methods)
 }
 
-func astMapTypeEq(x, y *ast.MapType) bool {
+func (c *comparer) astMapTypeEq(x, y *ast.MapType) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.Key, y.Key) && astExprEq(x.Value, y.Value)
+	return c.astExprEq(x.Key, y.Key) && c.astExprEq(x.Value, y.Value)
 }
 
-func astChanTypeEq(x, y *ast.ChanType) bool {
+func (c *comparer) astChanTypeEq(x, y *ast.ChanType) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return x.Dir == y.Dir && astExprEq(x.Value, y.Value)
+	return x.Dir == y.Dir && c.astExprEq(x.Value, y.Value)
 }
 
-func astCallExprEq(x, y *ast.CallExpr) bool {
+func (c *comparer) astCallExprEq(x, y *ast.CallExpr) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.Fun, y.Fun) &&
-		astExprSliceEq(x.Args, y.Args) &&
+	return c.astExprEq(x.Fun, y.Fun) &&
+		c.astExprSliceEq(x.Args, y.Args) &&
 		(x.Ellipsis == 0) == (y.Ellipsis == 0)
 }
 
-func astEllipsisEq(x, y *ast.Ellipsis) bool {
+func (c *comparer) astEllipsisEq(x, y *ast.Ellipsis) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.Elt, y.Elt)
+	return c.astExprEq(x.Elt, y.Elt)
 }
 
-func astUnaryExprEq(x, y *ast.UnaryExpr) bool {
+func (c *comparer) astUnaryExprEq(x, y *ast.UnaryExpr) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return x.Op == y.Op && astExprEq(x.X, y.X)
+	return x.Op == y.Op && c.astExprEq(x.X, y.X)
 }
 
-func astBinaryExprEq(x, y *ast.BinaryExpr) bool {
+func (c *comparer) astBinaryExprEq(x, y *ast.BinaryExpr) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return x.Op == y.Op &&
-		astExprEq(x.X, y.X) &&
-		astExprEq(x.Y, y.Y)
+	if x.Op != y.Op {
+		return false
+	}
+	if !c.commutative {
+		return c.astExprEq(x.X, y.X) && c.astExprEq(x.Y, y.Y)
+	}
+	s := c.save()
+	if c.astExprEq(x.X, y.X) && c.astExprEq(x.Y, y.Y) {
+		return true
+	}
+	c.restore(s)
+	return c.commutedEq(x, y)
 }
 
-func astParenExprEq(x, y *ast.ParenExpr) bool {
+func (c *comparer) astParenExprEq(x, y *ast.ParenExpr) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.X, y.X)
+	return c.astExprEq(x.X, y.X)
 }
 
-func astStarExprEq(x, y *ast.StarExpr) bool {
+func (c *comparer) astStarExprEq(x, y *ast.StarExpr) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.X, y.X)
+	return c.astExprEq(x.X, y.X)
 }
 
-func astFieldListEq(x, y *ast.FieldList) bool {
+func (c *comparer) astFieldListEq(x, y *ast.FieldList) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astFieldSliceEq(x.List, y.List)
+	return c.astFieldSliceEq(x.List, y.List)
 }
 
-func astEmptyStmtEq(x, y *ast.EmptyStmt) bool {
+func (c *comparer) astEmptyStmtEq(x, y *ast.EmptyStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
 	return x.Implicit == y.Implicit
 }
 
-func astLabeledStmtEq(x, y *ast.LabeledStmt) bool {
+func (c *comparer) astLabeledStmtEq(x, y *ast.LabeledStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astIdentEq(x.Label, y.Label) && astStmtEq(x.Stmt, y.Stmt)
+	return c.labelEq(x.Label, y.Label) && c.astStmtEq(x.Stmt, y.Stmt)
 }
 
-func astExprStmtEq(x, y *ast.ExprStmt) bool {
+func (c *comparer) astExprStmtEq(x, y *ast.ExprStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.X, y.X)
+	return c.astExprEq(x.X, y.X)
 }
 
-func astSendStmtEq(x, y *ast.SendStmt) bool {
+func (c *comparer) astSendStmtEq(x, y *ast.SendStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprEq(x.Chan, y.Chan) && astExprEq(x.Value, y.Value)
+	return c.astExprEq(x.Chan, y.Chan) && c.astExprEq(x.Value, y.Value)
 }
 
-func astDeclStmtEq(x, y *ast.DeclStmt) bool {
+func (c *comparer) astDeclStmtEq(x, y *ast.DeclStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astDeclEq(x.Decl, y.Decl)
+	if c.alpha {
+		return c.alphaDeclStmtEq(x, y)
+	}
+	return c.astDeclEq(x.Decl, y.Decl)
 }
 
-func astIncDecStmtEq(x, y *ast.IncDecStmt) bool {
+func (c *comparer) astIncDecStmtEq(x, y *ast.IncDecStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return x.Tok == y.Tok && astExprEq(x.X, y.X)
+	return x.Tok == y.Tok && c.astExprEq(x.X, y.X)
 }
 
-func astAssignStmtEq(x, y *ast.AssignStmt) bool {
+func (c *comparer) astAssignStmtEq(x, y *ast.AssignStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
+	if c.alpha {
+		return c.alphaAssignStmtEq(x, y)
+	}
 	return x.Tok == y.Tok &&
-		astExprSliceEq(x.Lhs, y.Lhs) &&
-		astExprSliceEq(x.Rhs, y.Rhs)
+		c.astExprSliceEq(x.Lhs, y.Lhs) &&
+		c.astExprSliceEq(x.Rhs, y.Rhs)
 }
 
-func astGoStmtEq(x, y *ast.GoStmt) bool {
+func (c *comparer) astGoStmtEq(x, y *ast.GoStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astCallExprEq(x.Call, y.Call)
+	return c.astCallExprEq(x.Call, y.Call)
 }
 
-func astDeferStmtEq(x, y *ast.DeferStmt) bool {
+func (c *comparer) astDeferStmtEq(x, y *ast.DeferStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astCallExprEq(x.Call, y.Call)
+	return c.astCallExprEq(x.Call, y.Call)
 }
 
-func astReturnStmtEq(x, y *ast.ReturnStmt) bool {
+func (c *comparer) astReturnStmtEq(x, y *ast.ReturnStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprSliceEq(x.Results, y.Results)
+	return c.astExprSliceEq(x.Results, y.Results)
 }
 
-func astBranchStmtEq(x, y *ast.BranchStmt) bool {
+func (c *comparer) astBranchStmtEq(x, y *ast.BranchStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return x.Tok == y.Tok && astIdentEq(x.Label, y.Label)
+	return x.Tok == y.Tok && c.labelEq(x.Label, y.Label)
 }
 
-func astIfStmtEq(x, y *ast.IfStmt) bool {
+func (c *comparer) astIfStmtEq(x, y *ast.IfStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astStmtEq(x.Init, y.Init) &&
-		astExprEq(x.Cond, y.Cond) &&
-		astBlockStmtEq(x.Body, y.Body) &&
-		astStmtEq(x.Else, y.Else)
+	if c.alpha {
+		defer c.leaveScope(c.enterScope())
+	}
+	return c.astStmtEq(x.Init, y.Init) &&
+		c.astExprEq(x.Cond, y.Cond) &&
+		c.astBlockStmtEq(x.Body, y.Body) &&
+		c.astStmtEq(x.Else, y.Else)
 }
 
-func astCaseClauseEq(x, y *ast.CaseClause) bool {
+func (c *comparer) astCaseClauseEq(x, y *ast.CaseClause) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astExprSliceEq(x.List, y.List) &&
-		astStmtSliceEq(x.Body, y.Body)
+	if c.alpha {
+		defer c.leaveScope(c.enterScope())
+	}
+	if c.xinfo != c.yinfo {
+		// Type switch clauses declare implicit variables.
+		c.pairObjects(c.xinfo.Implicits[x], c.yinfo.Implicits[y])
+	}
+	return c.astExprSliceEq(x.List, y.List) &&
+		c.astStmtSliceEq(x.Body, y.Body)
 }
 
-func astSwitchStmtEq(x, y *ast.SwitchStmt) bool {
+func (c *comparer) astSwitchStmtEq(x, y *ast.SwitchStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astStmtEq(x.Init, y.Init) &&
-		astExprEq(x.Tag, y.Tag) &&
-		astBlockStmtEq(x.Body, y.Body)
+	if c.alpha {
+		defer c.leaveScope(c.enterScope())
+	}
+	if c.ignoreOrder && unorderedSwitch(c.xinfo, x) && unorderedSwitch(c.yinfo, y) {
+		return c.astStmtEq(x.Init, y.Init) &&
+			c.astExprEq(x.Tag, y.Tag) &&
+			c.unorderedStmtSliceEq(x.Body.List, y.Body.List)
+	}
+	return c.astStmtEq(x.Init, y.Init) &&
+		c.astExprEq(x.Tag, y.Tag) &&
+		c.astBlockStmtEq(x.Body, y.Body)
 }
 
-func astTypeSwitchStmtEq(x, y *ast.TypeSwitchStmt) bool {
+func (c *comparer) astTypeSwitchStmtEq(x, y *ast.TypeSwitchStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astStmtEq(x.Init, y.Init) &&
-		astStmtEq(x.Assign, y.Assign) &&
-		astBlockStmtEq(x.Body, y.Body)
+	if c.alpha {
+		defer c.leaveScope(c.enterScope())
+	}
+	if c.ignoreOrder && unorderedTypeSwitch(c.xinfo, x) && unorderedTypeSwitch(c.yinfo, y) {
+		return c.astStmtEq(x.Init, y.Init) &&
+			c.astStmtEq(x.Assign, y.Assign) &&
+			c.unorderedStmtSliceEq(x.Body.List, y.Body.List)
+	}
+	return c.astStmtEq(x.Init, y.Init) &&
+		c.astStmtEq(x.Assign, y.Assign) &&
+		c.astBlockStmtEq(x.Body, y.Body)
 }
 
-func astCommClauseEq(x, y *ast.CommClause) bool {
+func (c *comparer) astCommClauseEq(x, y *ast.CommClause) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astStmtEq(x.Comm, y.Comm) && astStmtSliceEq(x.Body, y.Body)
+	if c.alpha {
+		defer c.leaveScope(c.enterScope())
+	}
+	return c.astStmtEq(x.Comm, y.Comm) && c.astStmtSliceEq(x.Body, y.Body)
 }
 
-func astSelectStmtEq(x, y *ast.SelectStmt) bool {
+func (c *comparer) astSelectStmtEq(x, y *ast.SelectStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astBlockStmtEq(x.Body, y.Body)
+	return c.astBlockStmtEq(x.Body, y.Body)
 }
 
-func astForStmtEq(x, y *ast.ForStmt) bool {
+func (c *comparer) astForStmtEq(x, y *ast.ForStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astStmtEq(x.Init, y.Init) &&
-		astExprEq(x.Cond, y.Cond) &&
-		astStmtEq(x.Post, y.Post) &&
-		astBlockStmtEq(x.Body, y.Body)
+	if c.alpha {
+		defer c.leaveScope(c.enterScope())
+	}
+	return c.astStmtEq(x.Init, y.Init) &&
+		c.astExprEq(x.Cond, y.Cond) &&
+		c.astStmtEq(x.Post, y.Post) &&
+		c.astBlockStmtEq(x.Body, y.Body)
 }
 
-func astRangeStmtEq(x, y *ast.RangeStmt) bool {
+func (c *comparer) astRangeStmtEq(x, y *ast.RangeStmt) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
+	if c.alpha {
+		return c.alphaRangeStmtEq(x, y)
+	}
 	return x.Tok == y.Tok &&
-		astExprEq(x.Key, y.Key) &&
-		astExprEq(x.Value, y.Value) &&
-		astExprEq(x.X, y.X) &&
-		astBlockStmtEq(x.Body, y.Body)
+		c.astExprEq(x.Key, y.Key) &&
+		c.astExprEq(x.Value, y.Value) &&
+		c.astExprEq(x.X, y.X) &&
+		c.astBlockStmtEq(x.Body, y.Body)
 }
 
-func astFuncDeclEq(x, y *ast.FuncDecl) bool {
+func (c *comparer) astFuncDeclEq(x, y *ast.FuncDecl) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astFieldListEq(x.Recv, y.Recv) &&
-		astIdentEq(x.Name, y.Name) &&
-		astFuncTypeEq(x.Type, y.Type) &&
-		astBlockStmtEq(x.Body, y.Body)
+	if c.alpha {
+		return c.alphaFuncDeclEq(x, y)
+	}
+	return c.astFieldListEq(x.Recv, y.Recv) &&
+		c.astIdentEq(x.Name, y.Name) &&
+		c.astFuncTypeEq(x.Type, y.Type) &&
+		c.astBlockStmtEq(x.Body, y.Body) &&
+		c.commentsEq(x.Doc, y.Doc)
 }
 
-func astGenDeclEq(x, y *ast.GenDecl) bool {
+func (c *comparer) astGenDeclEq(x, y *ast.GenDecl) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
 
-	if x.Tok != y.Tok {
-		return false
-	}
-	if len(x.Specs) != len(y.Specs) {
-		return false
+	if c.ignoreOrder && x.Tok == token.IMPORT && y.Tok == token.IMPORT {
+		return c.unorderedSpecSliceEq(x.Specs, y.Specs) &&
+			c.commentsEq(x.Doc, y.Doc)
 	}
+	return x.Tok == y.Tok &&
+		c.astSpecSliceEq(x.Specs, y.Specs) &&
+		c.commentsEq(x.Doc, y.Doc)
+}
 
-	switch x.Tok {
-	case token.IMPORT:
-		for i := range x.Specs {
-			xspec := x.Specs[i].(*ast.ImportSpec)
-			yspec := y.Specs[i].(*ast.ImportSpec)
-			if !astImportSpecEq(xspec, yspec) {
-				return false
-			}
-		}
-	case token.TYPE:
-		for i := range x.Specs {
-			xspec := x.Specs[i].(*ast.TypeSpec)
-			yspec := y.Specs[i].(*ast.TypeSpec)
-			if !astTypeSpecEq(xspec, yspec) {
-				return false
-			}
-		}
-	default:
-		for i := range x.Specs {
-			xspec := x.Specs[i].(*ast.ValueSpec)
-			yspec := y.Specs[i].(*ast.ValueSpec)
-			if !astValueSpecEq(xspec, yspec) {
-				return false
-			}
+func (c *comparer) astFileEq(x, y *ast.File) bool {
+	if x == nil || y == nil {
+		return x == y
+	}
+	xdecls, ydecls := x.Decls, y.Decls
+	if c.ignoreOrder {
+		var ok bool
+		ok, xdecls, ydecls = c.importDeclsEq(xdecls, ydecls)
+		if !ok {
+			return false
 		}
 	}
+	return c.astIdentEq(x.Name, y.Name) &&
+		buildConstraint(x) == buildConstraint(y) &&
+		c.astDeclSliceEq(xdecls, ydecls) &&
+		c.commentsEq(x.Doc, y.Doc) &&
+		(!c.comments || c.astCommentGroupSliceEq(freeComments(x), freeComments(y)))
+}
 
-	return true
+func (c *comparer) astImportSpecEq(x, y *ast.ImportSpec) bool {
+	if x == nil || y == nil {
+		return x == y
+	}
+	return c.astIdentEq(x.Name, y.Name) && c.astBasicLitEq(x.Path, y.Path) &&
+		c.commentsEq(x.Doc, y.Doc) &&
+		c.commentsEq(x.Comment, y.Comment)
 }
 
-func astImportSpecEq(x, y *ast.ImportSpec) bool {
+func (c *comparer) astTypeSpecEq(x, y *ast.TypeSpec) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astIdentEq(x.Name, y.Name) && astBasicLitEq(x.Path, y.Path)
+	return c.astIdentEq(x.Name, y.Name) && c.astExprEq(x.Type, y.Type) &&
+		c.astFieldListEq(forTypeSpec(x), forTypeSpec(y)) &&
+		c.commentsEq(x.Doc, y.Doc) &&
+		c.commentsEq(x.Comment, y.Comment)
 }
 
-func astTypeSpecEq(x, y *ast.TypeSpec) bool {
+func (c *comparer) astValueSpecEq(x, y *ast.ValueSpec) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astIdentEq(x.Name, y.Name) && astExprEq(x.Type, y.Type) &&
-		astFieldListEq(forTypeSpec(x), forTypeSpec(y))
+	return c.astIdentSliceEq(x.Names, y.Names) &&
+		c.astExprEq(x.Type, y.Type) &&
+		c.astExprSliceEq(x.Values, y.Values) &&
+		c.commentsEq(x.Doc, y.Doc) &&
+		c.commentsEq(x.Comment, y.Comment)
 }
 
-func astValueSpecEq(x, y *ast.ValueSpec) bool {
+func (c *comparer) astCommentGroupEq(x, y *ast.CommentGroup) bool {
 	if x == nil || y == nil {
 		return x == y
 	}
-	return astIdentSliceEq(x.Names, y.Names) &&
-		astExprEq(x.Type, y.Type) &&
-		astExprSliceEq(x.Values, y.Values)
+	return c.astCommentSliceEq(x.List, y.List)
+}
+
+func (c *comparer) astCommentEq(x, y *ast.Comment) bool {
+	if x == nil || y == nil {
+		return x == y
+	}
+	return x.Text == y.Text
+}
+
+// commentsEq compares the comments attached to a node.
+// Comments are only taken into account in comments-aware mode.
+func (c *comparer) commentsEq(x, y *ast.CommentGroup) bool {
+	return !c.comments || c.astCommentGroupEq(x, y)
 }
 
 // Compare slices for equallity.
@@ -706,54 +997,211 @@ func astValueSpecEq(x, y *ast.ValueSpec) bool {
 // hence instead of using adhoc comparison of values,
 // equallity functions that are defined above are used.
 
-func astIdentSliceEq(xs, ys []*ast.Ident) bool {
+func (c *comparer) astIdentSliceEq(xs, ys []*ast.Ident) bool {
 	if len(xs) != len(ys) {
 		return false
 	}
 	for i := range xs {
-		if !astIdentEq(xs[i], ys[i]) {
+		if !c.astIdentEq(xs[i], ys[i]) {
 			return false
 		}
 	}
 	return true
 }
 
-func astFieldSliceEq(xs, ys []*ast.Field) bool {
+func (c *comparer) astFieldSliceEq(xs, ys []*ast.Field) bool {
 	if len(xs) != len(ys) {
 		return false
 	}
 	for i := range xs {
-		if !astFieldEq(xs[i], ys[i]) {
+		if !c.astFieldEq(xs[i], ys[i]) {
 			return false
 		}
 	}
 	return true
 }
 
-func astStmtSliceEq(xs, ys []ast.Stmt) bool {
+func (c *comparer) astStmtSliceEq(xs, ys []ast.Stmt) bool {
+	if c.ungroupDecls {
+		return c.ungroupedStmtSliceEq(xs, ys)
+	}
 	if len(xs) != len(ys) {
 		return false
 	}
 	for i := range xs {
-		if !astStmtEq(xs[i], ys[i]) {
+		if !c.astStmtEq(xs[i], ys[i]) {
 			return false
 		}
 	}
 	return true
 }
 
-func astExprSliceEq(xs, ys []ast.Expr) bool {
+func (c *comparer) astExprSliceEq(xs, ys []ast.Expr) bool {
 	if len(xs) != len(ys) {
 		return false
 	}
 	for i := range xs {
-		if !astExprEq(xs[i], ys[i]) {
+		if !c.astExprEq(xs[i], ys[i]) {
 			return false
 		}
 	}
 	return true
 }
 
+func (c *comparer) astSpecSliceEq(xs, ys []ast.Spec) bool {
+	if len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		if !c.astSpecEq(xs[i], ys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *comparer) astCommentGroupSliceEq(xs, ys []*ast.CommentGroup) bool {
+	if len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		if !c.astCommentGroupEq(xs[i], ys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *comparer) astCommentSliceEq(xs, ys []*ast.Comment) bool {
+	if len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		if !c.astCommentEq(xs[i], ys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *comparer) astDeclSliceEq(xs, ys []ast.Decl) bool {
+	if c.ungroupDecls {
+		return c.ungroupedDeclSliceEq(xs, ys)
+	}
+	if len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		if !c.astDeclEq(xs[i], ys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// buildConstraint returns the canonical form of the f build constraint.
+//
+// The //go:build line takes precedence over // +build lines,
+// just like it does for the go command.
+// Only comments that precede the package clause are considered.
+// An empty string is returned if f has no (valid) build constraint.
+func buildConstraint(f *ast.File) string {
+	var plusBuild constraint.Expr
+	for _, g := range f.Comments {
+		if f.Package.IsValid() && g.Pos() >= f.Package {
+			break
+		}
+		for _, c := range g.List {
+			if !constraint.IsGoBuild(c.Text) && !constraint.IsPlusBuild(c.Text) {
+				continue
+			}
+			expr, err := constraint.Parse(c.Text)
+			if err != nil {
+				continue
+			}
+			if constraint.IsGoBuild(c.Text) {
+				return expr.String()
+			}
+			if plusBuild == nil {
+				plusBuild = expr
+			} else {
+				plusBuild = &constraint.AndExpr{X: plusBuild, Y: expr}
+			}
+		}
+	}
+	if plusBuild == nil {
+		return ""
+	}
+	return plusBuild.String()
+}
+
+// unparen returns e with any enclosing parentheses stripped.
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		paren, ok := e.(*ast.ParenExpr)
+		if !ok || paren == nil || paren.X == nil {
+			return e
+		}
+		e = paren.X
+	}
+}
+
+// identHook applies the modes that can replace x and y identifiers
+// name comparison. The ok result is false if none of them applies.
+func (c *comparer) identHook(x, y *ast.Ident) (eq, ok bool) {
+	if c.match != nil {
+		if name, ok := metavar(x); ok {
+			return c.match.bind(name, y), true
+		}
+	}
+	if c.equalFunc != nil {
+		if eq, ok := c.equalFunc(x, y); ok {
+			return eq, true
+		}
+	}
+	if c.alpha {
+		if eq, ok := c.boundEq(x, y); ok {
+			return eq, true
+		}
+	}
+	if c.xinfo != nil {
+		if eq, ok := c.objectEq(x, y); ok {
+			return eq, true
+		}
+	}
+	return false, false
+}
+
+// isLeaf reports whether x is an identifier or a basic literal.
+// Comparison hooks for them are called by astIdentEq and astBasicLitEq.
+func isLeaf(x ast.Expr) bool {
+	switch x.(type) {
+	case *ast.Ident, *ast.BasicLit:
+		return true
+	default:
+		return false
+	}
+}
+
+// freeComments returns f comments that are not attached to any node
+// as a doc or a line comment.
+func freeComments(f *ast.File) []*ast.CommentGroup {
+	attached := make(map[*ast.CommentGroup]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		if g, ok := n.(*ast.CommentGroup); ok {
+			attached[g] = true
+		}
+		return true
+	})
+	var free []*ast.CommentGroup
+	for _, g := range f.Comments {
+		if !attached[g] {
+			free = append(free, g)
+		}
+	}
+	return free
+}
+
 // forTypeSpec returns n.TypeParams.
 func forTypeSpec(n *ast.TypeSpec) *ast.FieldList {
 	if n == nil {
@@ -3,6 +3,7 @@ package astequal
 import (
 	"bytes"
 	"go/ast"
+	"go/parser"
 	"go/printer"
 	"go/token"
 	"reflect"
@@ -504,6 +505,78 @@ func TestEqualDeclString(t *testing.T) {
 		`func (b B) f() {}`)
 }
 
+func TestEqualFileString(t *testing.T) {
+	tests := []astEqualTest{
+		{`package foo`, `package foo`, true},
+		{`package foo`, `package bar`, false},
+		{`package foo; func f() {}`, `package foo; func f() {}`, true},
+		{`package foo; func f() {}`, `package bar; func f() {}`, false},
+		{`package foo; func f() {}`, `package foo; func g() {}`, false},
+		{`package foo; func f() {}`, `package foo`, false},
+		{`package foo; import "a"; var x A`, `package foo; import "a"; var x A`, true},
+		{`package foo; import "a"; var x A`, `package foo; import "b"; var x A`, false},
+
+		{"// Doc.\npackage foo", "package foo // Line.", true},
+		{"//go:build linux\n\npackage foo", "//go:build linux\n\npackage foo", true},
+		{"//go:build linux\n\npackage foo", "//go:build  (linux)\n\npackage foo", true},
+		{"//go:build linux\n\npackage foo", "// +build linux\n\npackage foo", true},
+		{"//go:build linux\n\npackage foo", "//go:build linux\n// +build darwin\n\npackage foo", true},
+		{"//go:build linux\n\npackage foo", "//go:build darwin\n\npackage foo", false},
+		{"//go:build linux\n\npackage foo", "package foo", false},
+		{"//go:build go1.21\n\npackage foo", "//go:build go1.22\n\npackage foo", false},
+		{"package foo\n//go:build linux\n", "package foo", true},
+	}
+
+	for _, test := range tests {
+		have := equalFileString(t, test.x, test.y)
+		if have != test.equal {
+			t.Errorf("equalFileString:\nx: %q\ny: %q\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.equal)
+		}
+	}
+
+	if !File(nil, nil) {
+		t.Errorf("nil files reported as not equal")
+	}
+	if !Node(parseFile(t, `package foo`), parseFile(t, `package foo`)) {
+		t.Errorf("Node: equal files reported as not equal")
+	}
+}
+
+func TestEqualSpecString(t *testing.T) {
+	tests := []astEqualTest{
+		{`import "a"`, `import "a"`, true},
+		{`import "a"`, `import "b"`, false},
+		{`import x "a"`, `import "a"`, false},
+		{`type x A`, `type x A`, true},
+		{`type x[T any] A`, `type x[T any] A`, true},
+		{`type x[T any] A`, `type x[T comparable] A`, false},
+		{`type x A`, `type y A`, false},
+		{`var x A`, `var x A`, true},
+		{`var x A`, `const x A`, true},
+		{`var x A = a`, `var x A = b`, false},
+		{`var x A`, `type x A`, false},
+		{`import "a"`, `var a A`, false},
+	}
+
+	for _, test := range tests {
+		x := strparse.Decl(test.x).(*ast.GenDecl).Specs[0]
+		y := strparse.Decl(test.y).(*ast.GenDecl).Specs[0]
+		if have := Spec(x, y); have != test.equal {
+			t.Errorf("Spec:\nx: %q\ny: %q\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.equal)
+		}
+		if have := Node(x, y); have != test.equal {
+			t.Errorf("Node:\nx: %q\ny: %q\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.equal)
+		}
+	}
+
+	if !Spec(nil, nil) {
+		t.Errorf("nil specs reported as not equal")
+	}
+}
+
 func equalExprString(x, y string) bool {
 	return Expr(strparse.Expr(x), strparse.Expr(y))
 }
@@ -516,6 +589,19 @@ func equalDeclString(x, y string) bool {
 	return Decl(strparse.Decl(x), strparse.Decl(y))
 }
 
+func equalFileString(t testing.TB, x, y string) bool {
+	return File(parseFile(t, x), parseFile(t, y))
+}
+
+func parseFile(t testing.TB, src string) *ast.File {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("parse %q: %v", src, err)
+	}
+	return f
+}
+
 func BenchmarkEqualExpr(b *testing.B) {
 	x := strparse.Expr(`f(x+y, a.b.c()/(-d), xs...)+first(g1)`)
 	y := strparse.Expr(`f(x+y, a.b.c()/(-d), xs...)+first(g2)`)
@@ -0,0 +1,432 @@
+// Package clones detects duplicated code in Go files.
+//
+// Two kinds of clones are reported: exact clones (type 1),
+// that differ only in formatting and comments,
+// and clones that differ only in identifiers and literals (type 2).
+//
+// The clone units are function declarations, function literals,
+// blocks and contiguous runs of statements inside a block.
+package clones
+
+import (
+	"go/ast"
+	"go/token"
+	"hash/fnv"
+	"reflect"
+	"sort"
+
+	"github.com/go-toolsmith/astequal"
+)
+
+// Type is a clone type.
+type Type int
+
+const (
+	// Type1 clones are equal, as reported by astequal.Node.
+	Type1 Type = 1
+
+	// Type2 clones differ only in identifiers and literals.
+	Type2 Type = 2
+)
+
+// DefaultMinSize is the default minimal clone size.
+const DefaultMinSize = 50
+
+// Clone is a single duplicated code fragment.
+type Clone struct {
+	// Nodes are the fragment nodes.
+	// It's a single node for functions and blocks,
+	// and several statements for statement runs.
+	Nodes []ast.Node
+
+	// Pos and End are the fragment boundaries.
+	Pos token.Position
+	End token.Position
+}
+
+// Class is a set of code fragments that are clones of each other.
+type Class struct {
+	// Type is the clones type.
+	// Type2 classes may contain exact clones as well,
+	// but not all of their clones are exactly equal.
+	Type Type
+
+	// Size is the fragments size, in AST nodes.
+	Size int
+
+	// Clones are the duplicated fragments, sorted by position.
+	Clones []Clone
+}
+
+// Option configures the clones search.
+type Option func(*config)
+
+type config struct {
+	minSize int
+}
+
+// MinSize sets the minimal clone size, in AST nodes.
+// Comments and field tags are not counted.
+//
+// The default is DefaultMinSize.
+func MinSize(n int) Option {
+	return func(cfg *config) {
+		cfg.minSize = n
+	}
+}
+
+// Find returns the clone classes found in the files.
+//
+// Only maximal clones are reported: statement runs are only reported
+// if they can't be extended with more equal statements, and a class
+// is omitted if all of its clones are inside the code of other
+// reported clones. Overlapping fragments are not reported as clones
+// of each other.
+//
+// Classes are sorted by size in decreasing order, then by position.
+func Find(fset *token.FileSet, files []*ast.File, opts ...Option) []Class {
+	cfg := config{minSize: DefaultMinSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	f := finder{
+		fset:    fset,
+		minSize: cfg.minSize,
+		infos:   make(map[ast.Node]nodeInfo),
+		kinds:   make(map[reflect.Type]uint64),
+		buckets: make(map[bucketKey][]*unit),
+	}
+	for _, file := range files {
+		f.collect(file)
+	}
+	f.addRuns()
+
+	var classes []*class
+	for _, units := range f.buckets {
+		if len(units) > 1 {
+			classes = append(classes, partition(units)...)
+		}
+	}
+	return report(fset, classes)
+}
+
+// unit is a clone candidate: a single node or a statements run.
+type unit struct {
+	node  ast.Node
+	stmts []ast.Stmt
+	size  int
+	pos   token.Pos
+	end   token.Pos
+}
+
+type bucketKey struct {
+	hash uint64
+	size int
+}
+
+type finder struct {
+	fset    *token.FileSet
+	minSize int
+	infos   map[ast.Node]nodeInfo
+	kinds   map[reflect.Type]uint64
+	buckets map[bucketKey][]*unit
+
+	// lists are the statement lists to find the runs in.
+	lists [][]ast.Stmt
+}
+
+// collect adds the file clone units to the buckets.
+func (f *finder) collect(file *ast.File) {
+	walkInfos(file, f.kind, func(n ast.Node, info nodeInfo) {
+		f.infos[n] = info
+		switch n := n.(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			f.add(&unit{node: n, pos: n.Pos(), end: n.End()}, info)
+		case *ast.BlockStmt:
+			f.add(&unit{node: n, pos: n.Pos(), end: n.End()}, info)
+			f.lists = append(f.lists, n.List)
+		case *ast.CaseClause:
+			f.lists = append(f.lists, n.Body)
+		case *ast.CommClause:
+			f.lists = append(f.lists, n.Body)
+		}
+	})
+}
+
+// addRuns adds the maximal runs of at least 2 statements
+// that are repeated in the statement lists.
+//
+// Runs are only extended from the pairs of equal statements
+// that are not preceded by equal statements, so a run is added
+// only if it can't be extended further. Runs in the same list
+// are cut before they overlap.
+func (f *finder) addRuns() {
+	type stmtRef struct {
+		list int
+		i    int
+	}
+	keys := make([][]bucketKey, len(f.lists))
+	occurs := make(map[bucketKey][]stmtRef)
+	for l, list := range f.lists {
+		keys[l] = make([]bucketKey, len(list))
+		for i, stmt := range list {
+			info := f.infos[stmt]
+			key := bucketKey{hash: info.hash, size: info.size}
+			keys[l][i] = key
+			occurs[key] = append(occurs[key], stmtRef{list: l, i: i})
+		}
+	}
+
+	type run struct {
+		list int
+		i    int
+		n    int
+	}
+	added := make(map[run]bool)
+	addRun := func(r run) {
+		if added[r] {
+			return
+		}
+		added[r] = true
+		stmts := f.lists[r.list][r.i : r.i+r.n]
+		h := fnv.New64a()
+		var info nodeInfo
+		for _, stmt := range stmts {
+			stmtInfo := f.infos[stmt]
+			writeUint64(h, stmtInfo.hash)
+			info.size += stmtInfo.size
+		}
+		info.hash = h.Sum64()
+		f.add(&unit{stmts: stmts, pos: stmts[0].Pos(), end: stmts[len(stmts)-1].End()}, info)
+	}
+
+	for _, refs := range occurs {
+		for a, x := range refs {
+			for _, y := range refs[a+1:] {
+				xkeys, ykeys := keys[x.list], keys[y.list]
+				if x.i > 0 && y.i > 0 && xkeys[x.i-1] == ykeys[y.i-1] {
+					// The run starts with the preceding statements.
+					continue
+				}
+				n := 1
+				for x.i+n < len(xkeys) && y.i+n < len(ykeys) &&
+					xkeys[x.i+n] == ykeys[y.i+n] &&
+					(x.list != y.list || x.i+n < y.i) {
+					n++
+				}
+				if n >= 2 {
+					addRun(run{list: x.list, i: x.i, n: n})
+					addRun(run{list: y.list, i: y.i, n: n})
+				}
+			}
+		}
+	}
+}
+
+func (f *finder) add(u *unit, info nodeInfo) {
+	if info.size < f.minSize {
+		return
+	}
+	u.size = info.size
+	key := bucketKey{hash: info.hash, size: info.size}
+	f.buckets[key] = append(f.buckets[key], u)
+}
+
+// kind returns the n node type hash.
+func (f *finder) kind(n ast.Node) uint64 {
+	typ := reflect.TypeOf(n)
+	h, ok := f.kinds[typ]
+	if !ok {
+		hash := fnv.New64a()
+		hash.Write([]byte(typ.String()))
+		h = hash.Sum64()
+		f.kinds[typ] = h
+	}
+	return h
+}
+
+type class struct {
+	units []*unit
+	exact bool
+
+	// end is the end of the rightmost unit.
+	end token.Pos
+}
+
+// renamedEq compares nodes ignoring identifiers and literals.
+var renamedEq = astequal.New(astequal.WithEqualFunc(func(x, y ast.Node) (bool, bool) {
+	switch x.(type) {
+	case *ast.Ident:
+		_, ok := y.(*ast.Ident)
+		return ok, true
+	case *ast.BasicLit:
+		_, ok := y.(*ast.BasicLit)
+		return ok, true
+	default:
+		return false, false
+	}
+}))
+
+// partition splits units with the same hash into classes of clones.
+// Overlapping units are not put in the same class.
+func partition(units []*unit) []*class {
+	sort.Slice(units, func(i, j int) bool {
+		return units[i].pos < units[j].pos
+	})
+	var classes []*class
+	for _, u := range units {
+		added := false
+		for _, c := range classes {
+			rep := c.units[0]
+			if !nodesEq(renamedEq, rep, u) {
+				continue
+			}
+			if !c.overlaps(u) {
+				c.units = append(c.units, u)
+				c.exact = c.exact && nodesEq(nil, rep, u)
+				c.end = u.end
+			}
+			added = true
+			break
+		}
+		if !added {
+			classes = append(classes, &class{units: []*unit{u}, exact: true, end: u.end})
+		}
+	}
+
+	list := classes[:0]
+	for _, c := range classes {
+		if len(c.units) > 1 {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+func nodesEq(cmp *astequal.Comparer, x, y *unit) bool {
+	if x.node != nil || y.node != nil {
+		return cmp.Node(x.node, y.node)
+	}
+	if len(x.stmts) != len(y.stmts) {
+		return false
+	}
+	for i := range x.stmts {
+		if !cmp.Stmt(x.stmts[i], y.stmts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// nodes returns the u nodes.
+func (u *unit) nodes() []ast.Node {
+	if u.node != nil {
+		return []ast.Node{u.node}
+	}
+	nodes := make([]ast.Node, len(u.stmts))
+	for i, stmt := range u.stmts {
+		nodes[i] = stmt
+	}
+	return nodes
+}
+
+// report converts maximal classes to the sorted Class list.
+func report(fset *token.FileSet, classes []*class) []Class {
+	// Bigger classes go first, so the nested ones
+	// can be checked against the already reported ones.
+	sort.Slice(classes, func(i, j int) bool {
+		x, y := classes[i], classes[j]
+		if x.units[0].size != y.units[0].size {
+			return x.units[0].size > y.units[0].size
+		}
+		return x.units[0].pos < y.units[0].pos
+	})
+
+	var reported coverage
+	var list []Class
+	for _, c := range classes {
+		if reported.covers(c) {
+			continue
+		}
+		for _, u := range c.units {
+			reported.add(u.pos, u.end)
+		}
+
+		typ := Type2
+		if c.exact {
+			typ = Type1
+		}
+		clones := make([]Clone, len(c.units))
+		for i, u := range c.units {
+			clones[i] = Clone{
+				Nodes: u.nodes(),
+				Pos:   fset.Position(u.pos),
+				End:   fset.Position(u.end),
+			}
+		}
+		list = append(list, Class{Type: typ, Size: c.units[0].size, Clones: clones})
+	}
+	return list
+}
+
+// coverage is the code of the reported units,
+// kept as sorted disjoint position ranges.
+type coverage []posRange
+
+type posRange struct {
+	pos token.Pos
+	end token.Pos
+}
+
+// add adds the [pos, end) range to cv.
+func (cv *coverage) add(pos, end token.Pos) {
+	s := *cv
+	i := sort.Search(len(s), func(i int) bool {
+		return s[i].end >= pos
+	})
+	j := i
+	for ; j < len(s) && s[j].pos <= end; j++ {
+		if s[j].pos < pos {
+			pos = s[j].pos
+		}
+		if s[j].end > end {
+			end = s[j].end
+		}
+	}
+	*cv = append(s[:i], append([]posRange{{pos: pos, end: end}}, s[j:]...)...)
+}
+
+// contains reports whether the [pos, end) range is inside cv.
+func (cv coverage) contains(pos, end token.Pos) bool {
+	i := sort.Search(len(cv), func(i int) bool {
+		return cv[i].end >= end
+	})
+	return i < len(cv) && cv[i].pos <= pos
+}
+
+// covers reports whether all c units are inside cv.
+// The statement runs may span several adjacent reported units.
+func (cv coverage) covers(c *class) bool {
+	for _, u := range c.units {
+		if u.node != nil {
+			if !cv.contains(u.pos, u.end) {
+				return false
+			}
+			continue
+		}
+		for _, stmt := range u.stmts {
+			if !cv.contains(stmt.Pos(), stmt.End()) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// overlaps reports whether u overlaps with any of c units.
+// Units are added in the position order, so only
+// the rightmost unit end has to be checked.
+func (c *class) overlaps(u *unit) bool {
+	return u.pos < c.end
+}
@@ -0,0 +1,160 @@
+package clones
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+const src1 = `package foo
+
+func sum(xs []int) int {
+	total := 0
+	for _, x := range xs {
+		if x > 0 {
+			total += x
+		}
+	}
+	return total
+}
+
+func sumFloats(vs []float64) float64 {
+	acc := 0.0
+	for _, v := range vs {
+		if v > 0 {
+			acc += v
+		}
+	}
+	return acc
+}
+
+func f(a, b int) {
+	println("start")
+	if a > b {
+		a, b = b, a
+	}
+	println(a, b)
+}
+`
+
+const src2 = `package foo
+
+// Doc comments don't matter.
+func sum2(xs []int) int {
+	total := 0
+	for _, x := range xs {
+		// Neither do inner comments.
+		if x > 0 {
+			total += x
+		}
+	}
+	return total
+}
+
+func g(a, b int) {
+	if a > b {
+		a, b = b, a
+	}
+	println(a, b)
+	println("end")
+}
+`
+
+func TestFind(t *testing.T) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for i, src := range []string{src1, src2} {
+		f, err := parser.ParseFile(fset, []string{"a.go", "b.go"}[i], src, parser.ParseComments)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		files = append(files, f)
+	}
+
+	classes := Find(fset, files, MinSize(12))
+
+	type clone struct {
+		file string
+		line int
+	}
+	want := []struct {
+		typ    Type
+		clones []clone
+	}{
+		{Type2, []clone{{"a.go", 3}, {"a.go", 13}, {"b.go", 4}}},
+		{Type1, []clone{{"a.go", 25}, {"b.go", 16}}},
+	}
+
+	if len(classes) != len(want) {
+		for _, c := range classes {
+			t.Logf("%v class of size %d:", c.Type, c.Size)
+			for _, clone := range c.Clones {
+				t.Logf("  %v - %v", clone.Pos, clone.End)
+			}
+		}
+		t.Fatalf("have %d classes, want %d", len(classes), len(want))
+	}
+	for i, c := range classes {
+		if c.Type != want[i].typ {
+			t.Errorf("class %d: have type %d, want %d", i, c.Type, want[i].typ)
+		}
+		if len(c.Clones) != len(want[i].clones) {
+			t.Errorf("class %d: have %d clones, want %d", i, len(c.Clones), len(want[i].clones))
+			continue
+		}
+		for j, clone := range c.Clones {
+			w := want[i].clones[j]
+			if clone.Pos.Filename != w.file || clone.Pos.Line != w.line {
+				t.Errorf("class %d clone %d: have %s:%d, want %s:%d",
+					i, j, clone.Pos.Filename, clone.Pos.Line, w.file, w.line)
+			}
+		}
+	}
+}
+
+func TestFindMinSize(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", src1, 0)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if classes := Find(fset, []*ast.File{f}); len(classes) != 0 {
+		t.Errorf("have %d classes with the default min size, want 0", len(classes))
+	}
+	if classes := Find(fset, []*ast.File{f}, MinSize(20)); len(classes) != 1 {
+		t.Errorf("have %d classes, want 1", len(classes))
+	}
+}
+
+func TestFindLongBlock(t *testing.T) {
+	// Every run of a generated block is a clone of any other run of
+	// the same length. Only the maximal ones, the block halves,
+	// must be reported, in a time that doesn't blow up.
+	for _, n := range []int{100, 400, 2000} {
+		var b strings.Builder
+		b.WriteString("package foo\n\nfunc init() {\n")
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&b, "\tx%d = g(%d)\n", i, i)
+		}
+		b.WriteString("}\n")
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "a.go", b.String(), 0)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+
+		classes := Find(fset, []*ast.File{f})
+		if len(classes) != 1 || len(classes[0].Clones) != 2 {
+			t.Fatalf("%d statements: have %d classes, want 1 class of 2 clones", n, len(classes))
+		}
+		for i, clone := range classes[0].Clones {
+			if want := 4 + i*n/2; clone.Pos.Line != want || len(clone.Nodes) != n/2 {
+				t.Errorf("%d statements: clone %d: have line %d with %d statements, want line %d with %d",
+					n, i, clone.Pos.Line, len(clone.Nodes), want, n/2)
+			}
+		}
+	}
+}
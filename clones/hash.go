@@ -0,0 +1,77 @@
+package clones
+
+import (
+	"encoding/binary"
+	"go/ast"
+	"go/token"
+	"hash"
+	"hash/fnv"
+
+	"github.com/go-toolsmith/astequal/internal/walk"
+)
+
+// nodeInfo is a node hash and size.
+//
+// The hash ignores identifiers and literals,
+// so type 2 clones have the same hash.
+type nodeInfo struct {
+	hash uint64
+	size int
+}
+
+// subtree is a node hash and size that are being computed.
+type subtree struct {
+	hash hash.Hash64
+	size int
+}
+
+// walkInfos calls visit in the post-order for every n subtree
+// with its hash and size.
+//
+// Comments and field tags are skipped,
+// as they are ignored by the comparison.
+func walkInfos(n ast.Node, kind func(ast.Node) uint64, visit func(ast.Node, nodeInfo)) {
+	leaf := func(n ast.Node) subtree {
+		h := fnv.New64a()
+		writeUint64(h, kind(n))
+		if tok, ok := operator(n); ok {
+			h.Write([]byte(tok.String()))
+		}
+		return subtree{hash: h, size: 1}
+	}
+	add := func(parent *subtree, child subtree) {
+		writeUint64(parent.hash, child.hash.Sum64())
+		parent.size += child.size
+	}
+	walk.Fold(n, leaf, add, func(n ast.Node, s subtree) bool {
+		visit(n, nodeInfo{hash: s.hash.Sum64(), size: s.size})
+		return true
+	})
+}
+
+// writeUint64 writes x to h.
+func writeUint64(h hash.Hash64, x uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], x)
+	h.Write(buf[:])
+}
+
+// operator returns the n operator or keyword token, if any.
+func operator(n ast.Node) (token.Token, bool) {
+	switch n := n.(type) {
+	case *ast.BinaryExpr:
+		return n.Op, true
+	case *ast.UnaryExpr:
+		return n.Op, true
+	case *ast.AssignStmt:
+		return n.Tok, true
+	case *ast.IncDecStmt:
+		return n.Tok, true
+	case *ast.BranchStmt:
+		return n.Tok, true
+	case *ast.GenDecl:
+		return n.Tok, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,224 @@
+// Command astequal compares two Go files or declarations
+// for structural equality.
+//
+// Usage:
+//
+//	astequal [flags] x y
+//
+// The x and y are either Go file names, or declarations
+// in the file.go:Name or file.go:Type.Method form.
+// Named declarations are functions, methods, types, vars and consts.
+//
+// By default, formatting and comments are ignored.
+// The exit status is 0 if x and y are equal, 1 if they differ,
+// and 2 in case of trouble. The diff is printed if they differ.
+// With the -json flag, a JSON comparison report is printed instead.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-toolsmith/astequal"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("astequal", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: astequal [flags] x y\n\n")
+		fmt.Fprintf(stderr, "x and y are file.go, file.go:Name or file.go:Type.Method\n\n")
+		flags.PrintDefaults()
+	}
+	var (
+		quiet       = flags.Bool("q", false, "don't print the diff")
+		jsonReport  = flags.Bool("json", false, "print a JSON report instead of the diff")
+		comments    = flags.Bool("comments", false, "compare comments")
+		tags        = flags.String("tags", "ignore", "struct tags comparison: ignore, exact or semantic")
+		literals    = flags.Bool("literals", false, "compare literals by their values")
+		parens      = flags.Bool("parens", false, "ignore redundant parentheses")
+		elide       = flags.Bool("elide", false, "ignore composite literal types that can be elided")
+		grouping    = flags.Bool("grouping", false, "ignore declarations grouping")
+		order       = flags.Bool("order", false, "ignore order of unordered constructs")
+		alpha       = flags.Bool("alpha", false, "ignore consistent renaming of local names")
+		commutative = flags.Bool("commutative", false, "ignore commutative operands order")
+	)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	var opts []astequal.Option
+	switch *tags {
+	case "ignore":
+	case "exact":
+		opts = append(opts, astequal.CompareTags(astequal.ExactTags))
+	case "semantic":
+		opts = append(opts, astequal.CompareTags(astequal.SemanticTags))
+	default:
+		fmt.Fprintf(stderr, "astequal: unknown -tags mode %q\n", *tags)
+		return 2
+	}
+	options := []struct {
+		enabled bool
+		opt     astequal.Option
+	}{
+		{*comments, astequal.CompareComments()},
+		{*literals, astequal.CompareLiteralValues()},
+		{*parens, astequal.IgnoreParens()},
+		{*elide, astequal.ElideCompositeTypes()},
+		{*grouping, astequal.IgnoreDeclGrouping()},
+		{*order, astequal.IgnoreOrder()},
+		{*alpha, astequal.AlphaEquivalence()},
+		{*commutative, astequal.CommutativeOperators()},
+	}
+	for _, o := range options {
+		if o.enabled {
+			opts = append(opts, o.opt)
+		}
+	}
+
+	mode := parser.SkipObjectResolution
+	if *comments {
+		mode |= parser.ParseComments
+	}
+	xfset, yfset := token.NewFileSet(), token.NewFileSet()
+	x, err := load(xfset, flags.Arg(0), mode)
+	if err != nil {
+		fmt.Fprintf(stderr, "astequal: %v\n", err)
+		return 2
+	}
+	y, err := load(yfset, flags.Arg(1), mode)
+	if err != nil {
+		fmt.Fprintf(stderr, "astequal: %v\n", err)
+		return 2
+	}
+
+	cmp := astequal.New(append(opts, astequal.WithFileSets(xfset, yfset))...)
+	if *jsonReport {
+		report := cmp.Report(x, y)
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(stderr, "astequal: %v\n", err)
+			return 2
+		}
+		if report.Equal {
+			return 0
+		}
+		return 1
+	}
+	if cmp.Node(x, y) {
+		return 0
+	}
+	if !*quiet {
+		diff := cmp.Diff(x, y)
+		fmt.Fprint(stdout, diff)
+		if diff != "" && !strings.HasSuffix(diff, "\n") {
+			fmt.Fprintln(stdout)
+		}
+	}
+	return 1
+}
+
+// load parses the file.go or file.go:Name arg.
+func load(fset *token.FileSet, arg string, mode parser.Mode) (ast.Node, error) {
+	filename, name := arg, ""
+	if _, err := os.Stat(arg); err != nil {
+		if i := strings.LastIndexByte(arg, ':'); i > 0 {
+			filename, name = arg[:i], arg[i+1:]
+		}
+	}
+	f, err := parser.ParseFile(fset, filename, nil, mode)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return f, nil
+	}
+	decl := lookup(f, name)
+	if decl == nil {
+		return nil, fmt.Errorf("%s: %s is not declared", filename, name)
+	}
+	return decl, nil
+}
+
+// lookup returns the f declaration with the given name.
+// Types, vars and consts are returned as specs,
+// so they compare equal whether they are declared in a group or not.
+func lookup(f *ast.File, name string) ast.Node {
+	recv, name := "", name
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		recv, name = name[:i], name[i+1:]
+	}
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Name.Name == name && recvName(decl) == recv {
+				return decl
+			}
+		case *ast.GenDecl:
+			if recv != "" {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				if declares(spec, name) {
+					return spec
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// recvName returns the decl receiver base type name, if any.
+func recvName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return ""
+	}
+	typ := decl.Recv.List[0].Type
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+		case *ast.ParenExpr:
+			typ = t.X
+		case *ast.IndexExpr:
+			typ = t.X
+		case *ast.IndexListExpr:
+			typ = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+func declares(spec ast.Spec, name string) bool {
+	switch spec := spec.(type) {
+	case *ast.TypeSpec:
+		return spec.Name.Name == name
+	case *ast.ValueSpec:
+		for _, id := range spec.Names {
+			if id.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	a := write("a.go", `package foo
+
+// F does something.
+func F(x int) int { return x + 1 }
+
+type T struct{ x int }
+
+func (t *T) M() int { return t.x }
+
+var (
+	v1 = 1
+	v2 = 2
+)
+`)
+	b := write("b.go", `package foo
+
+func F(x int) int {
+	// Reformatted.
+	return x + 1
+}
+
+type T struct {
+	x int
+}
+
+func (t T) M() int { return t.x * 2 }
+
+func G(x int) int { return (x + 1) }
+
+var v1 = 1
+`)
+
+	tests := []struct {
+		args []string
+		code int
+		diff bool
+	}{
+		{[]string{a + ":F", b + ":F"}, 0, false},
+		{[]string{"-comments", a + ":F", b + ":F"}, 1, true},
+		{[]string{a + ":T", b + ":T"}, 0, false},
+		{[]string{a + ":T.M", b + ":T.M"}, 1, true},
+		{[]string{"-q", a + ":T.M", b + ":T.M"}, 1, false},
+		{[]string{a + ":F", b + ":G"}, 1, true},
+		{[]string{"-parens", a + ":F", b + ":G"}, 1, true},
+		{[]string{a + ":v1", b + ":v1"}, 0, false},
+		{[]string{a, b}, 1, true},
+		{[]string{a, a}, 0, false},
+		{[]string{a + ":Missing", b + ":F"}, 2, false},
+		{[]string{a + ":F", filepath.Join(dir, "missing.go")}, 2, false},
+		{[]string{"-tags", "bad", a, b}, 2, false},
+		{[]string{a}, 2, false},
+		{[]string{"-json", a + ":F", b + ":F"}, 0, true},
+		{[]string{"-json", a + ":T.M", b + ":T.M"}, 1, true},
+	}
+
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(test.args, &stdout, &stderr)
+		name := strings.ReplaceAll(strings.Join(test.args, " "), dir+string(filepath.Separator), "")
+		if code != test.code {
+			t.Errorf("%s: have exit code %d, want %d\nstderr: %s",
+				name, code, test.code, stderr.String())
+		}
+		if have := stdout.Len() != 0; have != test.diff {
+			t.Errorf("%s: have diff %v, want %v\nstdout: %s",
+				name, have, test.diff, stdout.String())
+		}
+		if code == 2 && stderr.Len() == 0 {
+			t.Errorf("%s: no error message", name)
+		}
+	}
+}
+
+func TestRunDiff(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	if err := os.WriteFile(a, []byte("package foo\n\nfunc F() int {\n\treturn 1\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("package foo\n\nvar v = 1\n\nfunc F() int {\n\treturn 2\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{a + ":F", b + ":F"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("have exit code %d, want 1\nstderr: %s", code, stderr.String())
+	}
+	want := "--- " + a + "\n+++ " + b + "\n" +
+		"@@ -3,3 +5,3 @@ " + a + ":3-5 " + b + ":5-7\n" +
+		" func F() int {\n-\treturn 1\n+\treturn 2\n }\n"
+	if have := stdout.String(); have != want {
+		t.Errorf("diff:\nhave:\n%s\nwant:\n%s", have, want)
+	}
+}
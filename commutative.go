@@ -0,0 +1,152 @@
+package astequal
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// commutedEq compares x and y binary expressions with swapped operands
+// of y, if the operator is commutative for both of them.
+func (c *comparer) commutedEq(x, y *ast.BinaryExpr) bool {
+	return c.commutable(c.xinfo, x) &&
+		c.commutable(c.yinfo, y) &&
+		c.astExprEq(x.X, y.Y) &&
+		c.astExprEq(x.Y, y.X)
+}
+
+// commutedExplained reports whether unequal x and y binary expressions
+// differences should be explained with swapped operands of y.
+// That's the case if the operands can be swapped and one of the
+// swapped operand pairs is equal, while none of the original ones are.
+func (c *comparer) commutedExplained(x, y *ast.BinaryExpr) bool {
+	if !c.commutative || !c.commutable(c.xinfo, x) || !c.commutable(c.yinfo, y) {
+		return false
+	}
+	eq := func(x, y ast.Expr) bool {
+		s := c.save()
+		defer c.restore(s)
+		return c.astExprEq(x, y)
+	}
+	if eq(x.X, y.X) || eq(x.Y, y.Y) {
+		return false
+	}
+	return eq(x.X, y.Y) || eq(x.Y, y.X)
+}
+
+// commutable reports whether e operands can be swapped
+// without changing the expression meaning.
+//
+// Operands evaluation order is preserved: at least one of them
+// must be safe (see isSafe). For && and || both operands must be safe,
+// as the second one may not be evaluated at all.
+func (c *comparer) commutable(info *types.Info, e *ast.BinaryExpr) bool {
+	switch e.Op {
+	case token.EQL, token.NEQ, token.AND, token.OR, token.XOR, token.MUL:
+		return isSafe(info, e.X) || isSafe(info, e.Y)
+	case token.ADD:
+		// + is also a string concatenation.
+		return isNumeric(info, e) &&
+			(isSafe(info, e.X) || isSafe(info, e.Y))
+	case token.LAND, token.LOR:
+		return isSafe(info, e.X) && isSafe(info, e.Y)
+	default:
+		return false
+	}
+}
+
+// isNumeric reports whether e is a numeric expression.
+//
+// Without type information, e is numeric if one of its operands
+// is a numeric literal.
+func isNumeric(info *types.Info, e *ast.BinaryExpr) bool {
+	if info != nil {
+		if tv, ok := info.Types[e]; ok && tv.Type != nil {
+			basic, ok := tv.Type.Underlying().(*types.Basic)
+			return ok && basic.Info()&types.IsNumeric != 0
+		}
+	}
+	return isNumericLit(e.X) || isNumericLit(e.Y)
+}
+
+func isNumericLit(e ast.Expr) bool {
+	lit, ok := unparen(e).(*ast.BasicLit)
+	if !ok || lit == nil {
+		return false
+	}
+	switch lit.Kind {
+	case token.INT, token.FLOAT, token.IMAG, token.CHAR:
+		return true
+	default:
+		return false
+	}
+}
+
+// isSafe reports whether e evaluation can't have side effects or panic.
+//
+// This is a conservative check. Without type information only
+// identifiers, literals and operators that can't panic are safe.
+// Comparisons are only safe if one of the operands is a literal or nil,
+// as comparison of interfaces may panic.
+// With type information, comparisons of non-interface values,
+// qualified identifiers and field selections without pointer
+// indirections are safe as well.
+func isSafe(info *types.Info, e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return e != nil
+	case *ast.BasicLit:
+		return e != nil
+	case *ast.ParenExpr:
+		return e != nil && isSafe(info, e.X)
+	case *ast.UnaryExpr:
+		return e != nil && e.Op != token.ARROW && isSafe(info, e.X)
+	case *ast.BinaryExpr:
+		if e == nil || !isSafe(info, e.X) || !isSafe(info, e.Y) {
+			return false
+		}
+		switch e.Op {
+		case token.QUO, token.REM, token.SHL, token.SHR:
+			return false
+		case token.EQL, token.NEQ:
+			return isConstOrNil(e.X) || isConstOrNil(e.Y) ||
+				(isConcrete(info, e.X) && isConcrete(info, e.Y))
+		default:
+			return true
+		}
+	case *ast.SelectorExpr:
+		if e == nil || info == nil {
+			return false
+		}
+		if pkg, ok := e.X.(*ast.Ident); ok {
+			if _, ok := info.Uses[pkg].(*types.PkgName); ok {
+				return true
+			}
+		}
+		sel, ok := info.Selections[e]
+		return ok && sel.Kind() == types.FieldVal && !sel.Indirect() && isSafe(info, e.X)
+	default:
+		return false
+	}
+}
+
+// isConstOrNil reports whether e is a literal or nil.
+func isConstOrNil(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		return e != nil
+	case *ast.Ident:
+		return e != nil && e.Name == "nil"
+	default:
+		return false
+	}
+}
+
+// isConcrete reports whether e is known to have a non-interface type.
+func isConcrete(info *types.Info, e ast.Expr) bool {
+	if info == nil {
+		return false
+	}
+	tv, ok := info.Types[e]
+	return ok && tv.Type != nil && !types.IsInterface(tv.Type)
+}
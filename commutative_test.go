@@ -0,0 +1,145 @@
+package astequal
+
+import (
+	"go/ast"
+	"go/token"
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestCommutativeOperators(t *testing.T) {
+	tests := []struct {
+		x     string
+		y     string
+		equal bool
+	}{
+		{`a == b`, `b == a`, true},
+		{`a != b`, `b != a`, true},
+		{`a & b`, `b & a`, true},
+		{`a | b`, `b | a`, true},
+		{`a ^ b`, `b ^ a`, true},
+		{`a * b`, `b * a`, true},
+		{`a == b`, `a == b`, true},
+		{`a == b`, `b != a`, false},
+		{`a == b`, `c == a`, false},
+		{`a - b`, `b - a`, false},
+		{`a / b`, `b / a`, false},
+		{`a < b`, `b < a`, false},
+		{`a &^ b`, `b &^ a`, false},
+
+		// + may be a string concatenation.
+		{`a + b`, `b + a`, false},
+		{`a + 1`, `1 + a`, true},
+		{`a + 1.5`, `(1.5) + a`, false},
+		{`a + "s"`, `"s" + a`, false},
+
+		// Side effects order is preserved.
+		{`f() == a`, `a == f()`, true},
+		{`f() == g()`, `g() == f()`, false},
+		{`<-ch == a`, `a == <-ch`, true},
+		{`<-ch == <-ch2`, `<-ch2 == <-ch`, false},
+		{`a[i] == f()`, `f() == a[i]`, false},
+		{`-a * b`, `b * -a`, true},
+
+		// Both && and || operands must be safe.
+		{`a && b`, `b && a`, true},
+		{`a || !b`, `!b || a`, true},
+		{`a && f()`, `f() && a`, false},
+		{`p != nil && p.x > 0`, `p.x > 0 && p != nil`, false},
+		{`x > 0 && x < 10`, `x < 10 && x > 0`, true},
+		{`x == 1 && y == nil`, `y == nil && x == 1`, true},
+		{`x == y && z`, `z && x == y`, false},
+		{`a/b > 0 && c`, `c && a/b > 0`, false},
+
+		// Nested expressions.
+		{`f(a == b)`, `f(b == a)`, true},
+		{`(a == b) == (c & d)`, `(d & c) == (b == a)`, true},
+		{`a + b + c`, `a + c + b`, false},
+	}
+
+	cmp := New(CommutativeOperators())
+	for _, test := range tests {
+		x := strparse.Expr(test.x)
+		y := strparse.Expr(test.y)
+		if have := cmp.Expr(x, y); have != test.equal {
+			t.Errorf("Expr:\nx: %s\ny: %s\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.equal)
+		}
+		if Expr(x, y) && test.x != test.y {
+			t.Errorf("default Expr:\nx: %s\ny: %s\nhave: true", test.x, test.y)
+		}
+	}
+}
+
+func TestCommutativeOperatorsTypes(t *testing.T) {
+	const src = `package foo
+
+type T struct{ x int }
+
+func f(a, b int, s, q string, i, j interface{}, v T, p *T) {
+	_ = a + b
+	_ = b + a
+	_ = s + q
+	_ = q + s
+	_ = a == b && s == q
+	_ = s == q && a == b
+	_ = i == j && a > 0
+	_ = a > 0 && i == j
+	_ = v.x > 0 && a > 0
+	_ = a > 0 && v.x > 0
+	_ = p.x > 0 && a > 0
+	_ = a > 0 && p.x > 0
+}
+`
+	fset := token.NewFileSet()
+	f, info := typeCheck(t, fset, src)
+	stmts := f.Decls[len(f.Decls)-1].(*ast.FuncDecl).Body.List
+	rhs := func(i int) ast.Expr {
+		return stmts[i].(*ast.AssignStmt).Rhs[0]
+	}
+
+	tests := []struct {
+		name  string
+		i, j  int
+		equal bool
+	}{
+		{"numeric +", 0, 1, true},
+		{"string +", 2, 3, false},
+		{"concrete comparisons", 4, 5, true},
+		{"interface comparison", 6, 7, false},
+		{"field selection", 8, 9, true},
+		{"pointer indirection", 10, 11, false},
+	}
+
+	cmp := New(CommutativeOperators(), WithTypesInfo(info, nil))
+	for _, test := range tests {
+		if have := cmp.Expr(rhs(test.i), rhs(test.j)); have != test.equal {
+			t.Errorf("%s: have %v, want %v", test.name, have, test.equal)
+		}
+	}
+}
+
+func TestCommutativeOperatorsExplain(t *testing.T) {
+	tests := []struct {
+		x    string
+		y    string
+		want string
+	}{
+		{`a == b+1`, `b+2 == a`, `BinaryExpr.Y.Y: value mismatch`},
+		{`a + 1`, `a + 2`, `BinaryExpr.Y: value mismatch`},
+		{`a == b`, `c == d`, `BinaryExpr.X: name mismatch`},
+		{`f() == b`, `b == g()`, `BinaryExpr.X.Fun: name mismatch`},
+		// Operands of a possible string concatenation are not swapped.
+		{`f() + b`, `b + g()`, `BinaryExpr.X: kind mismatch`},
+	}
+
+	cmp := New(CommutativeOperators())
+	for _, test := range tests {
+		x := strparse.Expr(test.x)
+		y := strparse.Expr(test.y)
+		if m := cmp.Explain(x, y); m == nil || m.String() != test.want {
+			t.Errorf("Explain(%s, %s):\nhave: %v\nwant: %s", test.x, test.y, m, test.want)
+		}
+	}
+}
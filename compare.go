@@ -0,0 +1,598 @@
+package astequal
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// Compare returns an integer comparing two AST nodes.
+// The result is 0 if x and y are equal in the Node function sense,
+// -1 if x < y, and +1 if x > y.
+//
+// Compare defines a deterministic total order that only depends
+// on the nodes structure: nodes are ordered by their types first,
+// then by their fields in the source order.
+// A nil node is less than any other node.
+//
+// The only exception from the Node consistency are nodes
+// that are never equal: ast.BadExpr, ast.BadStmt, ast.BadDecl
+// and the types that Node doesn't support. They are ordered
+// by their positions and Compare returns 0 for the nodes
+// that occupy the same source range.
+func Compare(x, y ast.Node) int {
+	return compareNodes(x, y)
+}
+
+func compareNodes(x, y ast.Node) int {
+	if x == nil || y == nil {
+		switch {
+		case x == y:
+			return 0
+		case x == nil:
+			return -1
+		default:
+			return +1
+		}
+	}
+
+	if r := compareInt(int(nodeTagOf(x)), int(nodeTagOf(y))); r != 0 {
+		return r
+	}
+	xnil, ynil := isNilNode(x), isNilNode(y)
+	if xnil || ynil {
+		switch {
+		case xnil == ynil:
+			return 0
+		case xnil:
+			return -1
+		default:
+			return +1
+		}
+	}
+
+	switch x := x.(type) {
+	case *ast.Ident:
+		y := y.(*ast.Ident)
+		return compareString(x.Name, y.Name)
+	case *ast.BasicLit:
+		y := y.(*ast.BasicLit)
+		if r := compareInt(int(x.Kind), int(y.Kind)); r != 0 {
+			return r
+		}
+		return compareString(x.Value, y.Value)
+	case *ast.FuncLit:
+		y := y.(*ast.FuncLit)
+		if r := compareNodes(x.Type, y.Type); r != 0 {
+			return r
+		}
+		return compareNodes(x.Body, y.Body)
+	case *ast.CompositeLit:
+		y := y.(*ast.CompositeLit)
+		if r := compareNodes(x.Type, y.Type); r != 0 {
+			return r
+		}
+		return compareExprList(x.Elts, y.Elts)
+	case *ast.ParenExpr:
+		y := y.(*ast.ParenExpr)
+		return compareNodes(x.X, y.X)
+	case *ast.SelectorExpr:
+		y := y.(*ast.SelectorExpr)
+		if r := compareNodes(x.X, y.X); r != 0 {
+			return r
+		}
+		return compareNodes(x.Sel, y.Sel)
+	case *ast.IndexExpr:
+		y := y.(*ast.IndexExpr)
+		if r := compareNodes(x.X, y.X); r != 0 {
+			return r
+		}
+		return compareNodes(x.Index, y.Index)
+	case *ast.IndexListExpr:
+		y := y.(*ast.IndexListExpr)
+		if r := compareNodes(x.X, y.X); r != 0 {
+			return r
+		}
+		return compareExprList(x.Indices, y.Indices)
+	case *ast.SliceExpr:
+		y := y.(*ast.SliceExpr)
+		if r := compareNodes(x.X, y.X); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.Low, y.Low); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.High, y.High); r != 0 {
+			return r
+		}
+		return compareNodes(x.Max, y.Max)
+	case *ast.TypeAssertExpr:
+		y := y.(*ast.TypeAssertExpr)
+		if r := compareNodes(x.X, y.X); r != 0 {
+			return r
+		}
+		return compareNodes(x.Type, y.Type)
+	case *ast.CallExpr:
+		y := y.(*ast.CallExpr)
+		if r := compareNodes(x.Fun, y.Fun); r != 0 {
+			return r
+		}
+		if r := compareExprList(x.Args, y.Args); r != 0 {
+			return r
+		}
+		return compareBool(x.Ellipsis != 0, y.Ellipsis != 0)
+	case *ast.StarExpr:
+		y := y.(*ast.StarExpr)
+		return compareNodes(x.X, y.X)
+	case *ast.UnaryExpr:
+		y := y.(*ast.UnaryExpr)
+		if r := compareInt(int(x.Op), int(y.Op)); r != 0 {
+			return r
+		}
+		return compareNodes(x.X, y.X)
+	case *ast.BinaryExpr:
+		y := y.(*ast.BinaryExpr)
+		if r := compareInt(int(x.Op), int(y.Op)); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.X, y.X); r != 0 {
+			return r
+		}
+		return compareNodes(x.Y, y.Y)
+	case *ast.KeyValueExpr:
+		y := y.(*ast.KeyValueExpr)
+		if r := compareNodes(x.Key, y.Key); r != 0 {
+			return r
+		}
+		return compareNodes(x.Value, y.Value)
+	case *ast.ArrayType:
+		y := y.(*ast.ArrayType)
+		if r := compareNodes(x.Len, y.Len); r != 0 {
+			return r
+		}
+		return compareNodes(x.Elt, y.Elt)
+	case *ast.StructType:
+		y := y.(*ast.StructType)
+		return compareNodes(x.Fields, y.Fields)
+	case *ast.FuncType:
+		y := y.(*ast.FuncType)
+		if r := compareNodes(x.Params, y.Params); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.Results, y.Results); r != 0 {
+			return r
+		}
+		return compareNodes(x.TypeParams, y.TypeParams)
+	case *ast.InterfaceType:
+		y := y.(*ast.InterfaceType)
+		return compareNodes(x.Methods, y.Methods)
+	case *ast.MapType:
+		y := y.(*ast.MapType)
+		if r := compareNodes(x.Key, y.Key); r != 0 {
+			return r
+		}
+		return compareNodes(x.Value, y.Value)
+	case *ast.ChanType:
+		y := y.(*ast.ChanType)
+		if r := compareInt(int(x.Dir), int(y.Dir)); r != 0 {
+			return r
+		}
+		return compareNodes(x.Value, y.Value)
+	case *ast.Ellipsis:
+		y := y.(*ast.Ellipsis)
+		return compareNodes(x.Elt, y.Elt)
+
+	case *ast.ExprStmt:
+		y := y.(*ast.ExprStmt)
+		return compareNodes(x.X, y.X)
+	case *ast.SendStmt:
+		y := y.(*ast.SendStmt)
+		if r := compareNodes(x.Chan, y.Chan); r != 0 {
+			return r
+		}
+		return compareNodes(x.Value, y.Value)
+	case *ast.IncDecStmt:
+		y := y.(*ast.IncDecStmt)
+		if r := compareInt(int(x.Tok), int(y.Tok)); r != 0 {
+			return r
+		}
+		return compareNodes(x.X, y.X)
+	case *ast.AssignStmt:
+		y := y.(*ast.AssignStmt)
+		if r := compareInt(int(x.Tok), int(y.Tok)); r != 0 {
+			return r
+		}
+		if r := compareExprList(x.Lhs, y.Lhs); r != 0 {
+			return r
+		}
+		return compareExprList(x.Rhs, y.Rhs)
+	case *ast.GoStmt:
+		y := y.(*ast.GoStmt)
+		return compareNodes(x.Call, y.Call)
+	case *ast.DeferStmt:
+		y := y.(*ast.DeferStmt)
+		return compareNodes(x.Call, y.Call)
+	case *ast.ReturnStmt:
+		y := y.(*ast.ReturnStmt)
+		return compareExprList(x.Results, y.Results)
+	case *ast.BranchStmt:
+		y := y.(*ast.BranchStmt)
+		if r := compareInt(int(x.Tok), int(y.Tok)); r != 0 {
+			return r
+		}
+		return compareNodes(x.Label, y.Label)
+	case *ast.BlockStmt:
+		y := y.(*ast.BlockStmt)
+		return compareStmtList(x.List, y.List)
+	case *ast.IfStmt:
+		y := y.(*ast.IfStmt)
+		if r := compareNodes(x.Init, y.Init); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.Cond, y.Cond); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.Body, y.Body); r != 0 {
+			return r
+		}
+		return compareNodes(x.Else, y.Else)
+	case *ast.CaseClause:
+		y := y.(*ast.CaseClause)
+		if r := compareExprList(x.List, y.List); r != 0 {
+			return r
+		}
+		return compareStmtList(x.Body, y.Body)
+	case *ast.SwitchStmt:
+		y := y.(*ast.SwitchStmt)
+		if r := compareNodes(x.Init, y.Init); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.Tag, y.Tag); r != 0 {
+			return r
+		}
+		return compareNodes(x.Body, y.Body)
+	case *ast.TypeSwitchStmt:
+		y := y.(*ast.TypeSwitchStmt)
+		if r := compareNodes(x.Init, y.Init); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.Assign, y.Assign); r != 0 {
+			return r
+		}
+		return compareNodes(x.Body, y.Body)
+	case *ast.CommClause:
+		y := y.(*ast.CommClause)
+		if r := compareNodes(x.Comm, y.Comm); r != 0 {
+			return r
+		}
+		return compareStmtList(x.Body, y.Body)
+	case *ast.SelectStmt:
+		y := y.(*ast.SelectStmt)
+		return compareNodes(x.Body, y.Body)
+	case *ast.ForStmt:
+		y := y.(*ast.ForStmt)
+		if r := compareNodes(x.Init, y.Init); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.Cond, y.Cond); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.Post, y.Post); r != 0 {
+			return r
+		}
+		return compareNodes(x.Body, y.Body)
+	case *ast.RangeStmt:
+		y := y.(*ast.RangeStmt)
+		if r := compareInt(int(x.Tok), int(y.Tok)); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.Key, y.Key); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.Value, y.Value); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.X, y.X); r != 0 {
+			return r
+		}
+		return compareNodes(x.Body, y.Body)
+	case *ast.DeclStmt:
+		y := y.(*ast.DeclStmt)
+		return compareNodes(x.Decl, y.Decl)
+	case *ast.LabeledStmt:
+		y := y.(*ast.LabeledStmt)
+		if r := compareNodes(x.Label, y.Label); r != 0 {
+			return r
+		}
+		return compareNodes(x.Stmt, y.Stmt)
+	case *ast.EmptyStmt:
+		y := y.(*ast.EmptyStmt)
+		return compareBool(x.Implicit, y.Implicit)
+
+	case *ast.GenDecl:
+		y := y.(*ast.GenDecl)
+		if r := compareInt(int(x.Tok), int(y.Tok)); r != 0 {
+			return r
+		}
+		for i := 0; i < len(x.Specs) && i < len(y.Specs); i++ {
+			if r := compareNodes(x.Specs[i], y.Specs[i]); r != 0 {
+				return r
+			}
+		}
+		return compareInt(len(x.Specs), len(y.Specs))
+	case *ast.FuncDecl:
+		y := y.(*ast.FuncDecl)
+		if r := compareNodes(x.Recv, y.Recv); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.Name, y.Name); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.Type, y.Type); r != 0 {
+			return r
+		}
+		return compareNodes(x.Body, y.Body)
+
+	case *ast.ImportSpec:
+		y := y.(*ast.ImportSpec)
+		if r := compareNodes(x.Name, y.Name); r != 0 {
+			return r
+		}
+		return compareNodes(x.Path, y.Path)
+	case *ast.TypeSpec:
+		y := y.(*ast.TypeSpec)
+		if r := compareNodes(x.Name, y.Name); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.Type, y.Type); r != 0 {
+			return r
+		}
+		return compareNodes(x.TypeParams, y.TypeParams)
+	case *ast.ValueSpec:
+		y := y.(*ast.ValueSpec)
+		if r := compareIdentList(x.Names, y.Names); r != 0 {
+			return r
+		}
+		if r := compareNodes(x.Type, y.Type); r != 0 {
+			return r
+		}
+		return compareExprList(x.Values, y.Values)
+
+	case *ast.Field:
+		y := y.(*ast.Field)
+		if r := compareIdentList(x.Names, y.Names); r != 0 {
+			return r
+		}
+		return compareNodes(x.Type, y.Type)
+	case *ast.FieldList:
+		y := y.(*ast.FieldList)
+		for i := 0; i < len(x.List) && i < len(y.List); i++ {
+			if r := compareNodes(x.List[i], y.List[i]); r != 0 {
+				return r
+			}
+		}
+		return compareInt(len(x.List), len(y.List))
+	case *ast.File:
+		y := y.(*ast.File)
+		if r := compareNodes(x.Name, y.Name); r != 0 {
+			return r
+		}
+		if r := compareString(buildConstraint(x), buildConstraint(y)); r != 0 {
+			return r
+		}
+		for i := 0; i < len(x.Decls) && i < len(y.Decls); i++ {
+			if r := compareNodes(x.Decls[i], y.Decls[i]); r != 0 {
+				return r
+			}
+		}
+		return compareInt(len(x.Decls), len(y.Decls))
+
+	case *ast.CommentGroup:
+		y := y.(*ast.CommentGroup)
+		for i := 0; i < len(x.List) && i < len(y.List); i++ {
+			if r := compareNodes(x.List[i], y.List[i]); r != 0 {
+				return r
+			}
+		}
+		return compareInt(len(x.List), len(y.List))
+	case *ast.Comment:
+		y := y.(*ast.Comment)
+		return compareString(x.Text, y.Text)
+
+	default:
+		// Bad and unsupported nodes are never equal.
+		if r := comparePos(x.Pos(), y.Pos()); r != 0 {
+			return r
+		}
+		return comparePos(x.End(), y.End())
+	}
+}
+
+func compareExprList(xs, ys []ast.Expr) int {
+	for i := 0; i < len(xs) && i < len(ys); i++ {
+		if r := compareNodes(xs[i], ys[i]); r != 0 {
+			return r
+		}
+	}
+	return compareInt(len(xs), len(ys))
+}
+
+func compareStmtList(xs, ys []ast.Stmt) int {
+	for i := 0; i < len(xs) && i < len(ys); i++ {
+		if r := compareNodes(xs[i], ys[i]); r != 0 {
+			return r
+		}
+	}
+	return compareInt(len(xs), len(ys))
+}
+
+func compareIdentList(xs, ys []*ast.Ident) int {
+	for i := 0; i < len(xs) && i < len(ys); i++ {
+		if r := compareNodes(xs[i], ys[i]); r != 0 {
+			return r
+		}
+	}
+	return compareInt(len(xs), len(ys))
+}
+
+func compareInt(x, y int) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return +1
+	default:
+		return 0
+	}
+}
+
+func comparePos(x, y token.Pos) int {
+	return compareInt(int(x), int(y))
+}
+
+func compareString(x, y string) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return +1
+	default:
+		return 0
+	}
+}
+
+func compareBool(x, y bool) int {
+	switch {
+	case x == y:
+		return 0
+	case !x:
+		return -1
+	default:
+		return +1
+	}
+}
+
+// nodeTagOf returns the n type tag.
+func nodeTagOf(n ast.Node) nodeTag {
+	switch n.(type) {
+	case nil:
+		return tagNil
+
+	case *ast.BadExpr:
+		return tagBadExpr
+	case *ast.Ident:
+		return tagIdent
+	case *ast.BasicLit:
+		return tagBasicLit
+	case *ast.FuncLit:
+		return tagFuncLit
+	case *ast.CompositeLit:
+		return tagCompositeLit
+	case *ast.ParenExpr:
+		return tagParenExpr
+	case *ast.SelectorExpr:
+		return tagSelectorExpr
+	case *ast.IndexExpr:
+		return tagIndexExpr
+	case *ast.IndexListExpr:
+		return tagIndexListExpr
+	case *ast.SliceExpr:
+		return tagSliceExpr
+	case *ast.TypeAssertExpr:
+		return tagTypeAssertExpr
+	case *ast.CallExpr:
+		return tagCallExpr
+	case *ast.StarExpr:
+		return tagStarExpr
+	case *ast.UnaryExpr:
+		return tagUnaryExpr
+	case *ast.BinaryExpr:
+		return tagBinaryExpr
+	case *ast.KeyValueExpr:
+		return tagKeyValueExpr
+	case *ast.ArrayType:
+		return tagArrayType
+	case *ast.StructType:
+		return tagStructType
+	case *ast.FuncType:
+		return tagFuncType
+	case *ast.InterfaceType:
+		return tagInterfaceType
+	case *ast.MapType:
+		return tagMapType
+	case *ast.ChanType:
+		return tagChanType
+	case *ast.Ellipsis:
+		return tagEllipsis
+
+	case *ast.BadStmt:
+		return tagBadStmt
+	case *ast.ExprStmt:
+		return tagExprStmt
+	case *ast.SendStmt:
+		return tagSendStmt
+	case *ast.IncDecStmt:
+		return tagIncDecStmt
+	case *ast.AssignStmt:
+		return tagAssignStmt
+	case *ast.GoStmt:
+		return tagGoStmt
+	case *ast.DeferStmt:
+		return tagDeferStmt
+	case *ast.ReturnStmt:
+		return tagReturnStmt
+	case *ast.BranchStmt:
+		return tagBranchStmt
+	case *ast.BlockStmt:
+		return tagBlockStmt
+	case *ast.IfStmt:
+		return tagIfStmt
+	case *ast.CaseClause:
+		return tagCaseClause
+	case *ast.SwitchStmt:
+		return tagSwitchStmt
+	case *ast.TypeSwitchStmt:
+		return tagTypeSwitchStmt
+	case *ast.CommClause:
+		return tagCommClause
+	case *ast.SelectStmt:
+		return tagSelectStmt
+	case *ast.ForStmt:
+		return tagForStmt
+	case *ast.RangeStmt:
+		return tagRangeStmt
+	case *ast.DeclStmt:
+		return tagDeclStmt
+	case *ast.LabeledStmt:
+		return tagLabeledStmt
+	case *ast.EmptyStmt:
+		return tagEmptyStmt
+
+	case *ast.BadDecl:
+		return tagBadDecl
+	case *ast.GenDecl:
+		return tagGenDecl
+	case *ast.FuncDecl:
+		return tagFuncDecl
+
+	case *ast.ImportSpec:
+		return tagImportSpec
+	case *ast.TypeSpec:
+		return tagTypeSpec
+	case *ast.ValueSpec:
+		return tagValueSpec
+
+	case *ast.Field:
+		return tagField
+	case *ast.FieldList:
+		return tagFieldList
+	case *ast.File:
+		return tagFile
+	case *ast.CommentGroup:
+		return tagCommentGroup
+	case *ast.Comment:
+		return tagComment
+
+	default:
+		return tagUnknown
+	}
+}
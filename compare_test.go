@@ -0,0 +1,78 @@
+package astequal
+
+import (
+	"go/ast"
+	"sort"
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestCompareConsistency(t *testing.T) {
+	nodes := sampleNodes(t)
+
+	for _, x := range nodes {
+		for _, y := range nodes {
+			r := Compare(x, y)
+			if (r == 0) != Node(x, y) {
+				t.Errorf("Compare is %d, but Node is %v:\nx: %#v\ny: %#v", r, Node(x, y), x, y)
+			}
+			if r != -Compare(y, x) {
+				t.Errorf("Compare is not antisymmetric:\nx: %#v\ny: %#v", x, y)
+			}
+		}
+	}
+
+	// Sorted slice must be ordered for every pair, not just the neighbours.
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return Compare(nodes[i], nodes[j]) < 0
+	})
+	for i := range nodes {
+		for j := i + 1; j < len(nodes); j++ {
+			if Compare(nodes[i], nodes[j]) > 0 {
+				t.Fatalf("Compare is not transitive:\nx: %#v\ny: %#v", nodes[i], nodes[j])
+			}
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		x    string
+		y    string
+		want int
+	}{
+		{`a`, `a`, 0},
+		{`a`, `b`, -1},
+		{`b`, `a`, +1},
+		{`f(a)`, `f(a, b)`, -1},
+		{`f(a, b)`, `f(b)`, -1},
+		{`f(a)`, `f(a...)`, -1},
+		{`(a)`, `a`, +1},
+	}
+	for _, test := range tests {
+		have := Compare(strparse.Expr(test.x), strparse.Expr(test.y))
+		if have != test.want {
+			t.Errorf("Compare(%s, %s): have %d, want %d", test.x, test.y, have, test.want)
+		}
+	}
+
+	var typedNil *ast.Ident
+	if Compare(nil, typedNil) != -1 || Compare(typedNil, nil) != +1 {
+		t.Errorf("nil interface must be less than a typed nil")
+	}
+	if Compare(typedNil, strparse.Expr(`a`)) != -1 {
+		t.Errorf("nil pointer must be less than non-nil one")
+	}
+}
+
+func TestCompareBadNodes(t *testing.T) {
+	x := &ast.BadExpr{From: 1, To: 2}
+	y := &ast.BadExpr{From: 1, To: 3}
+	if Compare(x, y) != -1 || Compare(y, x) != +1 {
+		t.Errorf("bad nodes must be ordered by positions")
+	}
+	if Compare(x, x) != 0 {
+		t.Errorf("bad node must be equal to itself")
+	}
+}
@@ -0,0 +1,365 @@
+package astequal
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// Comparer performs configurable AST (deep) equality checks.
+//
+// A Comparer created without options behaves exactly like
+// the package-level functions, which remain the fastest way
+// to perform the default comparison.
+//
+// A Comparer is safe for concurrent use.
+type Comparer struct {
+	config config
+}
+
+// config describes the Comparer settings.
+type config struct {
+	// comments enables doc, line and free-floating comments comparison.
+	comments bool
+
+	// tags selects the struct field tags comparison mode.
+	tags TagMode
+
+	// literalValues enables basic literals comparison by their values.
+	literalValues bool
+
+	// alpha enables alpha-equivalence of locally bound identifiers.
+	alpha bool
+
+	// commutative enables operands swapping for commutative operators.
+	commutative bool
+
+	// ignoreParens enables redundant parentheses skipping.
+	ignoreParens bool
+
+	// elideTypes enables composite literal types elision awareness.
+	elideTypes bool
+
+	// ungroupDecls enables declarations grouping insensitivity.
+	ungroupDecls bool
+
+	// ignoreOrder enables unordered constructs comparison as multisets.
+	ignoreOrder bool
+
+	// xinfo and yinfo are used to resolve identifiers.
+	// Either both are nil or both are non-nil.
+	xinfo *types.Info
+	yinfo *types.Info
+
+	// equalFunc is a user-provided comparison hook.
+	equalFunc EqualFunc
+
+	// xfset and yfset are used to print Diff nodes.
+	xfset *token.FileSet
+	yfset *token.FileSet
+
+	// diffContext is the number of Diff context lines.
+	// It's only used if diffContextSet is true.
+	diffContext    int
+	diffContextSet bool
+}
+
+// Option configures a Comparer.
+type Option func(*config)
+
+// New returns a Comparer configured with the given options.
+func New(opts ...Option) *Comparer {
+	cmp := &Comparer{}
+	for _, opt := range opts {
+		opt(&cmp.config)
+	}
+	return cmp
+}
+
+// CompareComments makes the Comparer take comments into account.
+//
+// Doc and line comments of fields, function and general declarations,
+// and import, type and value specs are compared, as well as
+// free-floating comments of files (those that are not attached to any node).
+// Comments are compared by their text, positions are ignored.
+func CompareComments() Option {
+	return func(cfg *config) {
+		cfg.comments = true
+	}
+}
+
+// CompareTags makes the Comparer compare struct field tags
+// according to the given mode.
+func CompareTags(mode TagMode) Option {
+	return func(cfg *config) {
+		cfg.tags = mode
+	}
+}
+
+// CompareLiteralValues makes the Comparer compare basic literals
+// by their constant values instead of their spelling.
+//
+// With this option 0x10 is equal to 16, 1_000 to 1000,
+// '\x41' to 'A' and a raw string to the same interpreted one.
+// Literals of different kinds are still different:
+// 1 (an integer) is not equal to 1.0 (a floating-point number)
+// and 'A' (a rune) is not equal to 65.
+func CompareLiteralValues() Option {
+	return func(cfg *config) {
+		cfg.literalValues = true
+	}
+}
+
+// AlphaEquivalence makes the Comparer treat functions that differ only
+// by a consistent renaming of locally bound identifiers as equal.
+//
+// Locally bound identifiers are receivers, parameters, results,
+// type parameters, variables, constants and types declared inside
+// functions, and labels. For example, func(a int) int { return a+1 }
+// is equal to func(b int) int { return b+1 }.
+//
+// Free identifiers (including package-level names), selectors
+// and struct field names still have to match exactly.
+// An identifier key of a composite literal is equal if it matches
+// either as a struct field name or as a bound variable.
+// Blank identifiers are never bound to named ones.
+func AlphaEquivalence() Option {
+	return func(cfg *config) {
+		cfg.alpha = true
+	}
+}
+
+// CommutativeOperators makes the Comparer ignore the operands order
+// of commutative binary operators, so a == b is equal to b == a.
+//
+// Operands of ==, !=, &, |, ^ and * can be swapped if at least
+// one of them is safe: its evaluation can't have side effects or panic.
+// This way the evaluation order of the side effects is preserved.
+// Operands of + can be swapped under the same condition if the expression
+// is known to be numeric, as + is also a string concatenation.
+// Operands of && and || can be swapped only if both of them are safe,
+// as the second operand may not be evaluated at all.
+//
+// Without type information (see WithTypesInfo) the checks are conservative:
+// only identifiers, literals and non-panicking operators are considered safe
+// and + is considered numeric only if one of its operands is a numeric literal.
+//
+// Only the immediate operands are swapped, so a+b+c is not equal to a+c+b.
+func CommutativeOperators() Option {
+	return func(cfg *config) {
+		cfg.commutative = true
+	}
+}
+
+// IgnoreParens makes the Comparer see through the parentheses,
+// so (x) is equal to x and (a*b)+c is equal to a*b+c.
+//
+// The operators precedence is still respected, as it's encoded in the
+// tree structure: (a+b)*c is not equal to a+b*c.
+func IgnoreParens() Option {
+	return func(cfg *config) {
+		cfg.ignoreParens = true
+	}
+}
+
+// ElideCompositeTypes makes the Comparer treat the composite literal
+// element types that can be elided as if they were, so []T{T{1}}
+// is equal to []T{{1}} and []*T{&T{}} is equal to []*T{{}}.
+// Map keys are handled the same way.
+//
+// These are the simplifications applied by gofmt -s.
+func ElideCompositeTypes() Option {
+	return func(cfg *config) {
+		cfg.elideTypes = true
+	}
+}
+
+// IgnoreDeclGrouping makes the Comparer ignore the way declarations
+// are grouped, so var (a int; b string) is equal to var a int followed
+// by var b string, and var x, y int is equal to var x int; var y int.
+// This applies to the const, type and import declarations as well.
+//
+// The const implicit repetition is taken into account,
+// and consts that depend on iota must have the same iota value.
+// Specs that declare several names with a multi-value expression,
+// like var a, b = f(), are not split.
+func IgnoreDeclGrouping() Option {
+	return func(cfg *config) {
+		cfg.ungroupDecls = true
+	}
+}
+
+// IgnoreOrder makes the Comparer compare the constructs
+// that have no semantic order as multisets:
+//
+//   - import specs and declarations;
+//   - interface methods and embedded types;
+//   - keyed struct literal fields;
+//   - type switch clauses, if all case types are non-interface types;
+//   - switch clauses, if the switch has a tag, all case values are
+//     distinct non-boolean constants and there is no fallthrough.
+//
+// Without type information, any literal with identifier keys
+// is treated as a struct literal, only literals (with an optional sign)
+// are recognized as constant cases, and type switch clauses are compared
+// in order, as their case types are unknown.
+//
+// Evaluation order of struct literal field values is not preserved.
+func IgnoreOrder() Option {
+	return func(cfg *config) {
+		cfg.ignoreOrder = true
+	}
+}
+
+// WithTypesInfo makes the Comparer compare identifiers
+// by the objects they refer to instead of their names.
+//
+// The x info is used to resolve x nodes identifiers and the y info
+// is used for y nodes. If one of them is nil, the other is used for both.
+//
+// With this option, strs.Join (strings imported as strs) is equal
+// to strings.Join (or Join imported with a dot import), while two
+// different variables named the same are not.
+// Identifiers that are not recorded in Info.Uses (like declared names)
+// are compared by their names.
+//
+// If x and y are different infos, objects can't be compared by identity.
+// In this case package-level objects are matched by their kinds,
+// packages and names. Local objects declared within the compared nodes
+// are matched by their declarations, and the ones declared outside of them
+// by their declarations positions relative to the compared nodes.
+// Type switch variables are matched by their declarations
+// only if Info.Implicits is recorded.
+func WithTypesInfo(x, y *types.Info) Option {
+	switch {
+	case x == nil:
+		x = y
+	case y == nil:
+		y = x
+	}
+	return func(cfg *config) {
+		cfg.xinfo = x
+		cfg.yinfo = y
+	}
+}
+
+// DiffContext sets the number of unchanged lines
+// printed around the changes by the Comparer Diff method.
+//
+// The default is 3 lines.
+func DiffContext(n int) Option {
+	return func(cfg *config) {
+		cfg.diffContext = n
+		cfg.diffContextSet = true
+	}
+}
+
+// WithFileSets makes the Comparer Diff method print the nodes using
+// their original positions from the x and y file sets,
+// and refer to the source files and lines.
+//
+// If one of the file sets is nil, the other one is used for both.
+func WithFileSets(x, y *token.FileSet) Option {
+	if x == nil {
+		x = y
+	}
+	if y == nil {
+		y = x
+	}
+	return func(cfg *config) {
+		cfg.xfset = x
+		cfg.yfset = y
+	}
+}
+
+// EqualFunc is a custom comparison function for a pair of nodes.
+//
+// It reports whether x and y are equal and whether the comparison
+// was handled. Unhandled pairs are compared by the Comparer itself.
+type EqualFunc func(x, y ast.Node) (equal, handled bool)
+
+// WithEqualFunc installs a custom comparison hook.
+//
+// The hook is called before the Comparer compares a pair of non-nil
+// expressions, statements, declarations, specs, fields, identifiers
+// or basic literals. The x and y are not necessarily of the same type.
+//
+// This can be used to tweak the comparison of particular nodes,
+// for example, to treat some function calls as equivalent.
+// Nested nodes of a handled pair are not visited.
+func WithEqualFunc(f EqualFunc) Option {
+	return func(cfg *config) {
+		cfg.equalFunc = f
+	}
+}
+
+// Node reports whether two AST nodes are structurally (deep) equal.
+//
+// See also: Node function.
+func (cmp *Comparer) Node(x, y ast.Node) bool {
+	c := cmp.comparer(x, y)
+	return c.astNodeEq(x, y)
+}
+
+// Expr reports whether two AST expressions are structurally (deep) equal.
+//
+// See also: Expr function.
+func (cmp *Comparer) Expr(x, y ast.Expr) bool {
+	c := cmp.comparer(x, y)
+	return c.astExprEq(x, y)
+}
+
+// Stmt reports whether two AST statements are structurally (deep) equal.
+//
+// See also: Stmt function.
+func (cmp *Comparer) Stmt(x, y ast.Stmt) bool {
+	c := cmp.comparer(x, y)
+	return c.astStmtEq(x, y)
+}
+
+// Decl reports whether two AST declarations are structurally (deep) equal.
+//
+// See also: Decl function.
+func (cmp *Comparer) Decl(x, y ast.Decl) bool {
+	c := cmp.comparer(x, y)
+	return c.astDeclEq(x, y)
+}
+
+// Spec reports whether two AST specs are structurally (deep) equal.
+//
+// See also: Spec function.
+func (cmp *Comparer) Spec(x, y ast.Spec) bool {
+	c := cmp.comparer(x, y)
+	return c.astSpecEq(x, y)
+}
+
+// File reports whether two AST files are structurally (deep) equal.
+//
+// See also: File function.
+func (cmp *Comparer) File(x, y *ast.File) bool {
+	c := cmp.comparer(x, y)
+	return c.astFileEq(x, y)
+}
+
+// comparer returns a comparer that uses cmp settings
+// to compare the x and y root nodes.
+func (cmp *Comparer) comparer(x, y ast.Node) comparer {
+	if cmp == nil {
+		return comparer{}
+	}
+	c := newComparer(cmp.config)
+	if c.xinfo != c.yinfo {
+		c.xroot, c.yroot = rootPos(x), rootPos(y)
+	}
+	return c
+}
+
+// newComparer returns a comparer that uses cfg settings.
+func newComparer(cfg config) comparer {
+	hooked := cfg.ignoreParens ||
+		cfg.equalFunc != nil ||
+		cfg.alpha ||
+		cfg.xinfo != nil ||
+		cfg.literalValues
+	return comparer{config: cfg, hooked: hooked}
+}
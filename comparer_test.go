@@ -0,0 +1,157 @@
+package astequal
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestComparerDefault(t *testing.T) {
+	x := strparse.Stmt(`x := []int{1, 2, 3}`)
+	y := strparse.Stmt(`x := []int{1, 2, 3}`)
+	z := strparse.Stmt(`x := []int{1, 2}`)
+
+	for _, cmp := range []*Comparer{nil, {}, New()} {
+		if !cmp.Node(x, y) || !cmp.Stmt(x, y) {
+			t.Errorf("%#v: equal nodes reported as not equal", cmp)
+		}
+		if cmp.Node(x, z) || cmp.Stmt(x, z) {
+			t.Errorf("%#v: different nodes reported as equal", cmp)
+		}
+	}
+}
+
+func TestComparerComments(t *testing.T) {
+	tests := []struct {
+		x     string
+		y     string
+		equal bool
+	}{
+		{"package foo", "package foo", true},
+		{"// Doc.\npackage foo", "// Doc.\npackage foo", true},
+		{"// Doc.\npackage foo", "// Changed.\npackage foo", false},
+		{"// Doc.\npackage foo", "package foo", false},
+		{"package foo\n// Doc.\nfunc f() {}", "package foo\n// Doc.\nfunc f() {}", true},
+		{"package foo\n// Doc.\nfunc f() {}", "package foo\n// Changed.\nfunc f() {}", false},
+		{"package foo\n// Doc.\nfunc f() {}", "package foo\nfunc f() {}", false},
+		{"package foo\n// Doc.\nvar x int", "package foo\n// Changed.\nvar x int", false},
+		{"package foo\nvar (\n// Doc.\nx int\n)", "package foo\nvar (\n// Changed.\nx int\n)", false},
+		{"package foo\nvar x int // Line.", "package foo\nvar x int // Changed.", false},
+		{"package foo\nvar x int // Line.", "package foo\nvar x int // Line.", true},
+		{"package foo\ntype T int // Line.", "package foo\ntype T int // Changed.", false},
+		{"package foo\n// Doc.\ntype T int", "package foo\n// Doc.\ntype T int", true},
+		{"package foo\nimport \"a\" // Line.", "package foo\nimport \"a\" // Changed.", false},
+		{"package foo\ntype T struct {\n// Doc.\nx int\n}", "package foo\ntype T struct {\n// Changed.\nx int\n}", false},
+		{"package foo\ntype T struct {\nx int // Line.\n}", "package foo\ntype T struct {\nx int // Changed.\n}", false},
+		{"package foo\nfunc f() {\n// Free.\n}", "package foo\nfunc f() {\n// Free.\n}", true},
+		{"package foo\nfunc f() {\n// Free.\n}", "package foo\nfunc f() {\n// Changed.\n}", false},
+		{"package foo\nfunc f() {\n// Free.\n}", "package foo\nfunc f() {\n}", false},
+		{"package foo\nfunc f() {}\n// Free.", "package foo\nfunc f() {}\n/* Free. */", false},
+	}
+
+	cmp := New(CompareComments())
+	for _, test := range tests {
+		x := parseFile(t, test.x)
+		y := parseFile(t, test.y)
+		if have := cmp.File(x, y); have != test.equal {
+			t.Errorf("File:\nx: %q\ny: %q\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.equal)
+		}
+		if !File(x, y) {
+			t.Errorf("File without comments:\nx: %q\ny: %q\nhave: false\nwant: true",
+				test.x, test.y)
+		}
+	}
+}
+
+func TestEqualComments(t *testing.T) {
+	f := parseFile(t, "// A.\npackage foo\n// B.\n// C.\nvar x int\n// A.")
+	a1, b, a2 := f.Comments[0], f.Comments[1], f.Comments[2]
+
+	if !Node(a1, a2) || !Node(a1.List[0], a2.List[0]) {
+		t.Errorf("equal comments reported as not equal")
+	}
+	if Node(a1, b) || Node(a1.List[0], b.List[0]) {
+		t.Errorf("different comments reported as equal")
+	}
+	if Node(a1, (*ast.CommentGroup)(nil)) {
+		t.Errorf("nil comment group reported as equal")
+	}
+}
+
+func TestComparerEqualFunc(t *testing.T) {
+	// Treat log.Print and log.Println calls as equivalent.
+	isPrint := func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		return ok && (sel.Sel.Name == "Print" || sel.Sel.Name == "Println")
+	}
+	cmp := New(WithEqualFunc(func(x, y ast.Node) (bool, bool) {
+		if isPrint(x) && isPrint(y) {
+			return Expr(x.(*ast.CallExpr).Args[0], y.(*ast.CallExpr).Args[0]), true
+		}
+		return false, false
+	}))
+
+	tests := []struct {
+		x     string
+		y     string
+		equal bool
+	}{
+		{`log.Print(x)`, `log.Println(x)`, true},
+		{`f(log.Print(x))`, `f(log.Println(x))`, true},
+		{`log.Print(x)`, `log.Println(y)`, false},
+		{`log.Print(x)`, `log.Fatal(x)`, false},
+		{`{log.Print(x); return}`, `{log.Println(x); return}`, true},
+	}
+	for _, test := range tests {
+		x := strparse.Stmt(test.x)
+		y := strparse.Stmt(test.y)
+		if have := cmp.Stmt(x, y); have != test.equal {
+			t.Errorf("Stmt:\nx: %s\ny: %s\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.equal)
+		}
+	}
+
+	// Ignore all identifier names.
+	cmp = New(WithEqualFunc(func(x, y ast.Node) (bool, bool) {
+		_, ok1 := x.(*ast.Ident)
+		_, ok2 := y.(*ast.Ident)
+		return ok1 && ok2, ok1 || ok2
+	}))
+	if !cmp.Decl(strparse.Decl(`func f(a int) { g(a.b) }`), strparse.Decl(`func h(x int) { y(z.w) }`)) {
+		t.Errorf("identifiers are not ignored")
+	}
+	if cmp.Decl(strparse.Decl(`func f(a int) { g(a.b) }`), strparse.Decl(`func h(x int) { y(1) }`)) {
+		t.Errorf("different declarations reported as equal")
+	}
+}
+
+func TestComparerFastPath(t *testing.T) {
+	plain := []*Comparer{
+		nil,
+		New(),
+		New(CompareComments(), CompareTags(SemanticTags), IgnoreOrder()),
+	}
+	for _, cmp := range plain {
+		if c := cmp.comparer(nil, nil); c.hooked {
+			t.Errorf("%#v: per-node hooks are enabled", cmp)
+		}
+	}
+
+	hooked := []*Comparer{
+		New(IgnoreParens()),
+		New(AlphaEquivalence()),
+		New(CompareLiteralValues()),
+		New(WithEqualFunc(func(x, y ast.Node) (bool, bool) { return false, false })),
+	}
+	for _, cmp := range hooked {
+		if c := cmp.comparer(nil, nil); !c.hooked {
+			t.Errorf("%#v: per-node hooks are disabled", cmp)
+		}
+	}
+}
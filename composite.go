@@ -0,0 +1,207 @@
+package astequal
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+)
+
+// compositeLitEq compares composite literals with types that
+// may be elided in favor of the xt and yt enclosing literal element types.
+// The xt and yt are nil if there is no enclosing literal.
+func (c *comparer) compositeLitEq(x, y *ast.CompositeLit, xt, yt ast.Expr) bool {
+	if x.Type != nil || y.Type != nil {
+		if !c.astExprEq(x.Type, y.Type) {
+			return false
+		}
+	}
+	if x.Type != nil {
+		xt = x.Type
+	}
+	if y.Type != nil {
+		yt = y.Type
+	}
+	if len(x.Elts) != len(y.Elts) {
+		return false
+	}
+	if c.ignoreOrder && unorderedLit(c.xinfo, x) && unorderedLit(c.yinfo, y) {
+		return c.unorderedExprSliceEq(x.Elts, y.Elts)
+	}
+	xkey, xelt := elementTypes(xt)
+	ykey, yelt := elementTypes(yt)
+	for i := range x.Elts {
+		xkv, ok1 := x.Elts[i].(*ast.KeyValueExpr)
+		ykv, ok2 := y.Elts[i].(*ast.KeyValueExpr)
+		if !ok1 || !ok2 || xkv == nil || ykv == nil {
+			if !c.elementEq(x.Elts[i], y.Elts[i], xelt, yelt) {
+				return false
+			}
+			continue
+		}
+		keyEq := c.keyEq(xkv.Key, ykv.Key)
+		if xkey != nil || ykey != nil {
+			keyEq = c.elementEq(xkv.Key, ykv.Key, xkey, ykey)
+		}
+		if !keyEq || !c.elementEq(xkv.Value, ykv.Value, xelt, yelt) {
+			return false
+		}
+	}
+	return true
+}
+
+// elementEq compares composite literal elements (or map keys)
+// of the xt and yt types.
+func (c *comparer) elementEq(x, y, xt, yt ast.Expr) bool {
+	xlit := elidable(x, xt)
+	ylit := elidable(y, yt)
+	if xlit == nil || ylit == nil {
+		return c.astExprEq(x, y)
+	}
+	return c.compositeLitEq(elided(xlit), elided(ylit), derefType(xt), derefType(yt))
+}
+
+// explainCompositeLit is like compositeLitEq, but explains x and y
+// differences. If elided is true, x and y types are known to be
+// the xt and yt element types, so they are not compared.
+func (c *comparer) explainCompositeLit(x, y *ast.CompositeLit, xt, yt ast.Expr, elided bool) *Mismatch {
+	var typ explainEdge
+	if !elided && (x.Type != nil || y.Type != nil) {
+		typ = edge("Type", x.Type, y.Type)
+	}
+	if x.Type != nil {
+		xt = x.Type
+	}
+	if y.Type != nil {
+		yt = y.Type
+	}
+	elts := listEdge("Elts", exprNodes(x.Elts), exprNodes(y.Elts))
+	elts.unordered = c.ignoreOrder && unorderedLit(c.xinfo, x) && unorderedLit(c.yinfo, y)
+	if len(x.Elts) != len(y.Elts) || elts.unordered {
+		return c.explainEdges(x, y, typ, elts)
+	}
+
+	xkey, xelt := elementTypes(xt)
+	ykey, yelt := elementTypes(yt)
+	edges := []explainEdge{typ}
+	for i := range x.Elts {
+		name := fmt.Sprintf("Elts[%d]", i)
+		xkv, ok1 := x.Elts[i].(*ast.KeyValueExpr)
+		ykv, ok2 := y.Elts[i].(*ast.KeyValueExpr)
+		if !ok1 || !ok2 || xkv == nil || ykv == nil {
+			edges = append(edges, c.elementEdge(name, x.Elts[i], y.Elts[i], xelt, yelt))
+			continue
+		}
+		key := c.keyEdge(name+".Key", xkv.Key, ykv.Key)
+		if xkey != nil || ykey != nil {
+			key = c.elementEdge(name+".Key", xkv.Key, ykv.Key, xkey, ykey)
+		}
+		edges = append(edges, key,
+			c.elementEdge(name+".Value", xkv.Value, ykv.Value, xelt, yelt))
+	}
+	return c.explainEdges(x, y, edges...)
+}
+
+// elementEdge returns an edge that explains composite literal elements
+// (or map keys) of the xt and yt types, see elementEq.
+func (c *comparer) elementEdge(name string, x, y, xt, yt ast.Expr) explainEdge {
+	e := edge(name, x, y)
+	e.explain = func() *Mismatch {
+		if c.elementEq(x, y, xt, yt) {
+			return nil
+		}
+		xlit := elidable(x, xt)
+		ylit := elidable(y, yt)
+		if xlit == nil || ylit == nil {
+			return c.explainNode(x, y)
+		}
+		m := c.explainCompositeLit(xlit, ylit, derefType(xt), derefType(yt), true)
+		if m == nil {
+			return c.mismatch(x, y, OtherMismatch)
+		}
+		if xlit != x {
+			// The &T{} literal.
+			m.prefix(".X")
+		}
+		return m
+	}
+	return e
+}
+
+// keyEdge returns an edge that explains composite literal keys,
+// see keyEq.
+func (c *comparer) keyEdge(name string, x, y ast.Expr) explainEdge {
+	e := edge(name, x, y)
+	e.explain = func() *Mismatch {
+		if c.keyEq(x, y) {
+			return nil
+		}
+		return c.explainNode(x, y)
+	}
+	return e
+}
+
+// elementTypes returns the key and element types of the
+// array, slice or map type t. The key type is nil for arrays and slices.
+func elementTypes(t ast.Expr) (key, elt ast.Expr) {
+	switch t := unparen(t).(type) {
+	case *ast.ArrayType:
+		if t != nil {
+			return nil, t.Elt
+		}
+	case *ast.MapType:
+		if t != nil {
+			return t.Key, t.Value
+		}
+	}
+	return nil, nil
+}
+
+// elidable returns the composite literal e if its type can be
+// elided as the t element type (like T{} for T, or &T{} for *T).
+// Literals with an already elided type are returned as well.
+func elidable(e, t ast.Expr) *ast.CompositeLit {
+	if t == nil {
+		return nil
+	}
+	if u, ok := e.(*ast.UnaryExpr); ok && u != nil && u.Op == token.AND {
+		star, ok := unparen(t).(*ast.StarExpr)
+		if !ok || star == nil {
+			return nil
+		}
+		e, t = u.X, star.X
+	}
+	lit, ok := e.(*ast.CompositeLit)
+	if !ok || lit == nil {
+		return nil
+	}
+	if lit.Type != nil && !sameType(lit.Type, t) {
+		return nil
+	}
+	return lit
+}
+
+// elided returns lit with its type elided.
+func elided(lit *ast.CompositeLit) *ast.CompositeLit {
+	if lit.Type == nil {
+		return lit
+	}
+	lit2 := *lit
+	lit2.Type = nil
+	return &lit2
+}
+
+// derefType returns the base type of the pointer type t,
+// or t itself if it's not a pointer.
+func derefType(t ast.Expr) ast.Expr {
+	if star, ok := unparen(t).(*ast.StarExpr); ok && star != nil {
+		return star.X
+	}
+	return t
+}
+
+// sameType reports whether x and y, both taken from the same tree,
+// spell the same type.
+func sameType(x, y ast.Expr) bool {
+	var c comparer
+	return c.astExprEq(x, y)
+}
@@ -0,0 +1,77 @@
+package astequal
+
+import (
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestElideCompositeTypes(t *testing.T) {
+	tests := []struct {
+		x     string
+		y     string
+		equal bool
+	}{
+		{`[]T{T{1}}`, `[]T{{1}}`, true},
+		{`[]T{{1}}`, `[]T{T{1}}`, true},
+		{`[]T{T{1}}`, `[]T{T{1}}`, true},
+		{`[2]T{T{1}, {2}}`, `[2]T{{1}, T{2}}`, true},
+		{`[]*T{&T{}}`, `[]*T{{}}`, true},
+		{`[]*T{&T{X: 1}}`, `[]*T{&T{X: 1}}`, true},
+		{`map[K]V{K{1}: V{2}}`, `map[K]V{{1}: {2}}`, true},
+		{`map[string]V{"a": V{2}}`, `map[string]V{"a": {2}}`, true},
+		{`map[*K]*V{&K{1}: &V{2}}`, `map[*K]*V{{1}: {2}}`, true},
+		{`[][]int{[]int{1}, []int{2}}`, `[][]int{{1}, {2}}`, true},
+		{`[][]T{[]T{T{1}}}`, `[][]T{{{1}}}`, true},
+		{`[]T{0: T{1}}`, `[]T{0: {1}}`, true},
+		{`[]pkg.T{pkg.T{}}`, `[]pkg.T{{}}`, true},
+
+		{`[]T{U{1}}`, `[]T{{1}}`, false},
+		{`[]T{T{1}}`, `[]T{{2}}`, false},
+		{`[]*T{T{}}`, `[]*T{{}}`, false},
+		{`[]T{&T{}}`, `[]T{{}}`, false},
+		{`[]T{T{1}}`, `[]U{{1}}`, false},
+		{`[]T{{1}}`, `[]T{{1}, {2}}`, false},
+		{`T{X: T{}}`, `T{X: {}}`, false},
+	}
+
+	cmp := New(ElideCompositeTypes())
+	for _, test := range tests {
+		x := strparse.Expr(test.x)
+		y := strparse.Expr(test.y)
+		if have := cmp.Expr(x, y); have != test.equal {
+			t.Errorf("Expr:\nx: %s\ny: %s\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.equal)
+		}
+	}
+
+	x := strparse.Expr(`[]T{T{1}}`)
+	y := strparse.Expr(`[]T{{1}}`)
+	if Expr(x, y) {
+		t.Errorf("default Expr must compare composite literal types")
+	}
+}
+
+func TestElideCompositeTypesExplain(t *testing.T) {
+	tests := []struct {
+		x    string
+		y    string
+		want string
+	}{
+		{`[]T{T{1}}`, `[]T{{2}}`, `CompositeLit.Elts[0].Elts[0]: value mismatch`},
+		{`[]*T{&T{1}}`, `[]*T{{2}}`, `CompositeLit.Elts[0].X.Elts[0]: value mismatch`},
+		{`map[K]V{K{1}: {2}}`, `map[K]V{{3}: V{2}}`, `CompositeLit.Elts[0].Key.Elts[0]: value mismatch`},
+		{`[]T{U{1}}`, `[]T{{1}}`, `CompositeLit.Elts[0].Type: nil mismatch`},
+		{`[]T{T{1}}`, `[]U{{1}}`, `CompositeLit.Type.Elt: name mismatch`},
+		{`[]T{{1}}`, `[]T{{1}, {2}}`, `CompositeLit.Elts: length mismatch`},
+	}
+
+	cmp := New(ElideCompositeTypes())
+	for _, test := range tests {
+		x := strparse.Expr(test.x)
+		y := strparse.Expr(test.y)
+		if m := cmp.Explain(x, y); m == nil || m.String() != test.want {
+			t.Errorf("Explain(%s, %s):\nhave: %v\nwant: %s", test.x, test.y, m, test.want)
+		}
+	}
+}
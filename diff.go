@@ -2,22 +2,653 @@ package astequal
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"go/ast"
 	"go/format"
+	"go/printer"
+	"go/scanner"
 	"go/token"
-
-	"github.com/google/go-cmp/cmp"
+	"strings"
 )
 
+// defaultDiffContext is the default number of diff context lines.
+const defaultDiffContext = 3
+
+// Diff returns a unified diff of the x and y printed forms,
+// or an empty string if they are printed identically and equal.
+//
+// The diff has --- x and +++ y headers and 3 context lines.
+//
+// Nodes that can't be printed faithfully are still reported,
+// see DiffErr for details.
 func Diff(x, y ast.Node) string {
+	var cmp *Comparer
+	return cmp.Diff(x, y)
+}
+
+// DiffErr is like Diff, but also returns an error if x or y
+// can't be printed faithfully.
+//
+// A nil node is printed as an empty text and is not an error.
+// A node that contains bad nodes (like ast.BadExpr) is printed
+// with a trailing comment line that names them,
+// so the diff is never empty, and an error that wraps ErrBadNode
+// is returned.
+// A node rejected by the printer (like a hand-built node with
+// missing fields) is printed as a single comment line with the
+// printer error, and an error that wraps ErrUnprintable is returned.
+// The same goes for nodes the printer would mangle: ones with
+// ILLEGAL operators or tokens, and empty identifiers.
+//
+// If x and y are not equal, but are printed identically
+// (like implicit and explicit empty statements), a trailing comment
+// line is added to the y text, and an error that wraps
+// ErrPrintedIdentically is returned.
+//
+// If both x and y are problematic, the x error is returned.
+func DiffErr(x, y ast.Node) (string, error) {
+	var cmp *Comparer
+	return cmp.DiffErr(x, y)
+}
+
+// Diff is like Diff function, but uses the Comparer diff settings.
+//
+// With WithFileSets, the nodes are printed using their original
+// positions, the headers are the file names and the hunks are
+// labeled with the file:line ranges they correspond to.
+//
+// See DiffContext and WithFileSets.
+func (cmp *Comparer) Diff(x, y ast.Node) string {
+	diff, _ := cmp.DiffErr(x, y)
+	return diff
+}
+
+// DiffErr is like DiffErr function, but uses the Comparer diff settings.
+//
+// See Comparer.Diff.
+func (cmp *Comparer) DiffErr(x, y ast.Node) (string, error) {
+	var cfg config
+	if cmp != nil {
+		cfg = cmp.config
+	}
+	context := defaultDiffContext
+	if cfg.diffContextSet {
+		context = cfg.diffContext
+	}
+	xtext, xerr := newDiffText("x", cfg.xfset, x)
+	ytext, yerr := newDiffText("y", cfg.yfset, y)
+	err := xerr
+	if err == nil {
+		err = yerr
+	}
+	if xtext.text == ytext.text {
+		c := cmp.comparer(x, y)
+		if !c.astNodeEq(x, y) {
+			identical := fmt.Errorf("astequal: %s and %s: %w", xtext.name, ytext.name, ErrPrintedIdentically)
+			ytext.addLine("// " + identical.Error())
+			if err == nil {
+				err = identical
+			}
+		}
+	}
+	return unifiedDiff(xtext, ytext, context), err
+}
+
+var (
+	// ErrBadNode is returned by DiffErr for nodes that contain bad nodes.
+	ErrBadNode = errors.New("bad node")
+
+	// ErrUnprintable is returned by DiffErr for nodes
+	// that are rejected or would be mangled by the printer.
+	ErrUnprintable = errors.New("unprintable node")
+
+	// ErrPrintedIdentically is returned by DiffErr for unequal nodes
+	// that are printed identically.
+	ErrPrintedIdentically = errors.New("unequal nodes printed identically")
+)
+
+// diffText is a printed node.
+type diffText struct {
+	// name is the file name or a default x or y name.
+	name string
+
+	// text is the printed node, terminated by a newline.
+	text string
+
+	// srcLines are the source line numbers of the text lines.
+	// It's nil if they are unknown.
+	srcLines []int
+}
+
+func newDiffText(name string, fset *token.FileSet, n ast.Node) (diffText, error) {
+	if isNilNode(n) {
+		return diffText{name: name}, nil
+	}
+	withPos := fset != nil && n.Pos().IsValid()
+	if withPos {
+		if filename := fset.Position(n.Pos()).Filename; filename != "" {
+			name = filename
+		}
+	} else {
+		fset = token.NewFileSet()
+	}
+
+	text, err := printNode(fset, n, withPos)
+	if err == nil {
+		err = malformedNode(n)
+	}
+	if err != nil {
+		err = fmt.Errorf("astequal: %s: %w: %v", name, ErrUnprintable, err)
+		return diffText{name: name, text: "// " + err.Error() + "\n"}, err
+	}
+	t := diffText{name: name, text: text}
+	if withPos {
+		t.srcLines = sourceLines(fset, n, text)
+	}
+
+	if bad := badNode(n); bad != nil {
+		err := fmt.Errorf("astequal: %s contains %w (%s)", name, ErrBadNode, nodeTypeName(bad))
+		t.addLine("// " + err.Error())
+		return t, err
+	}
+	return t, nil
+}
+
+// addLine adds a trailing line to the t text.
+// It's mapped to the last source line, if any.
+func (t *diffText) addLine(line string) {
+	t.text += line + "\n"
+	if len(t.srcLines) != 0 {
+		t.srcLines = append(t.srcLines, t.srcLines[len(t.srcLines)-1])
+	}
+}
+
+// printNode returns n printed form, terminated by a newline.
+//
+// If withPos is true, the printer is used directly, as format.Node
+// may sort imports, which breaks the tokens order (see sourceLines).
+func printNode(fset *token.FileSet, n ast.Node, withPos bool) (text string, err error) {
+	defer func() {
+		// The printer may panic on malformed nodes.
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
 	var buf bytes.Buffer
-	format.Node(&buf, token.NewFileSet(), x)
-	s1 := buf.String()
+	if withPos {
+		cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+		err = cfg.Fprint(&buf, fset, n)
+	} else {
+		err = format.Node(&buf, fset, n)
+	}
+	if err != nil {
+		return "", err
+	}
+	if buf.Len() != 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	return buf.String(), nil
+}
+
+// badNode returns the first bad node inside n, if any.
+func badNode(n ast.Node) ast.Node {
+	var bad ast.Node
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.BadExpr, *ast.BadStmt, *ast.BadDecl:
+			if bad == nil {
+				bad = n
+			}
+		}
+		return bad == nil
+	})
+	return bad
+}
+
+// malformedNode returns an error for the first node inside n
+// that the printer would mangle, if any.
+func malformedNode(n ast.Node) error {
+	var err error
+	ast.Inspect(n, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		var tok token.Token = -1
+		switch n := n.(type) {
+		case *ast.Ident:
+			if n.Name == "" {
+				err = errors.New("empty Ident")
+			}
+		case *ast.BinaryExpr:
+			tok = n.Op
+		case *ast.UnaryExpr:
+			tok = n.Op
+		case *ast.AssignStmt:
+			tok = n.Tok
+		case *ast.IncDecStmt:
+			tok = n.Tok
+		case *ast.BranchStmt:
+			tok = n.Tok
+		case *ast.GenDecl:
+			tok = n.Tok
+		case *ast.RangeStmt:
+			// The token is ILLEGAL if there is no key.
+			if n.Key != nil {
+				tok = n.Tok
+			}
+		}
+		if tok == token.ILLEGAL {
+			err = fmt.Errorf("%s with ILLEGAL token", nodeTypeName(n))
+		}
+		return err == nil
+	})
+	return err
+}
+
+// sourceLines maps the text lines, printed from n, to the n source lines.
+//
+// Identifiers and literals are printed in the same order they appear
+// in the source, so the lines with them are mapped to their source lines.
+// Other lines are mapped relatively to the nearest preceding mapped line.
+// It returns nil if the text and n tokens don't correspond.
+func sourceLines(fset *token.FileSet, n ast.Node, text string) []int {
+	var leaves []token.Pos
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Comment, *ast.CommentGroup:
+			return false
+		case *ast.Ident:
+			leaves = append(leaves, n.Pos())
+		case *ast.BasicLit:
+			leaves = append(leaves, n.Pos())
+		}
+		return true
+	})
+
+	nlines := strings.Count(text, "\n")
+	lines := make([]int, nlines)
+	textFile := token.NewFileSet().AddFile("", -1, len(text))
+	var s scanner.Scanner
+	s.Init(textFile, []byte(text), nil, 0)
+	i := 0
+	for {
+		pos, tok, _ := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.IDENT && !tok.IsLiteral() {
+			continue
+		}
+		if i == len(leaves) || !leaves[i].IsValid() {
+			return nil
+		}
+		line := textFile.Line(pos) - 1
+		if line < nlines && lines[line] == 0 {
+			lines[line] = fset.Position(leaves[i]).Line
+		}
+		i++
+	}
+	if i != len(leaves) {
+		return nil
+	}
+
+	// Lines without tokens follow the previous mapped line.
+	// Leading lines precede the first mapped line.
+	first := -1
+	for i, line := range lines {
+		if line != 0 {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+	for i := first - 1; i >= 0; i-- {
+		lines[i] = lines[i+1] - 1
+	}
+	for i := first + 1; i < len(lines); i++ {
+		if lines[i] == 0 {
+			lines[i] = lines[i-1] + 1
+		}
+	}
+	return lines
+}
+
+// unifiedDiff returns the unified diff of x and y texts,
+// with the given number of context lines around the changes.
+func unifiedDiff(x, y diffText, context int) string {
+	if x.text == y.text {
+		return ""
+	}
+	xlines, ylines := splitLines(x.text), splitLines(y.text)
+	edits := diffLines(xlines, ylines)
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", x.name, y.name)
+	for _, h := range hunks(edits, context) {
+		h.write(&buf, x, y, xlines, ylines)
+	}
+	return buf.String()
+}
 
-	buf.Reset()
-	format.Node(&buf, token.NewFileSet(), y)
-	s2 := buf.String()
+// splitLines splits s into lines, keeping the line terminators.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editKind is a line diff operation.
+type editKind int
+
+const (
+	editKeep editKind = iota
+	editDelete
+	editInsert
+)
+
+// lineEdit is a single line diff operation.
+// The x and y are the line indexes in the old and new texts;
+// only x is meaningful for deletions and only y for insertions.
+type lineEdit struct {
+	kind editKind
+	x    int
+	y    int
+}
+
+// diffLines returns the shortest edit script that turns xs into ys,
+// computed with the linear space variant of the Myers algorithm.
+//
+// Within each group of adjacent changes, deletions precede insertions.
+func diffLines(xs, ys []string) []lineEdit {
+	size := len(xs) + len(ys) + 3
+	d := &differ{xs: xs, ys: ys, fv: make([]int, size), bv: make([]int, size)}
+	d.compare(0, len(xs), 0, len(ys))
+	return groupChanges(d.edits)
+}
+
+// differ is a diffLines state.
+type differ struct {
+	xs []string
+	ys []string
+
+	// fv and bv are the furthest forward and backward
+	// reaching paths by their diagonals, see middle.
+	fv []int
+	bv []int
+
+	edits []lineEdit
+}
+
+// compare appends the edit script that turns xs[x0:x1] into ys[y0:y1].
+func (d *differ) compare(x0, x1, y0, y1 int) {
+	for x0 < x1 && y0 < y1 && d.xs[x0] == d.ys[y0] {
+		d.edits = append(d.edits, lineEdit{kind: editKeep, x: x0, y: y0})
+		x0++
+		y0++
+	}
+	suffix := 0
+	for x1 > x0 && y1 > y0 && d.xs[x1-1] == d.ys[y1-1] {
+		x1--
+		y1--
+		suffix++
+	}
+
+	switch {
+	case x0 == x1:
+		for y := y0; y < y1; y++ {
+			d.edits = append(d.edits, lineEdit{kind: editInsert, x: x0, y: y})
+		}
+	case y0 == y1:
+		for x := x0; x < x1; x++ {
+			d.edits = append(d.edits, lineEdit{kind: editDelete, x: x, y: y0})
+		}
+	default:
+		// Without the common prefix and suffix, at least 2 edits
+		// are needed, so both halves are smaller.
+		x, y := d.middle(x0, x1, y0, y1)
+		d.compare(x0, x, y0, y)
+		d.compare(x, x1, y, y1)
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.edits = append(d.edits, lineEdit{kind: editKeep, x: x1 + i, y: y1 + i})
+	}
+}
+
+// middle returns a point that splits a shortest edit script
+// that turns xs[x0:x1] into ys[y0:y1] into two halves,
+// neither of which is empty.
+//
+// The forward paths start at (x0, y0) and fv[k] is the furthest
+// x offset on the k diagonal. The backward paths start at (x1, y1)
+// and bv[k] is the furthest offset from x1 on the k diagonal,
+// counted from the end. The paths of the same length grow until
+// they overlap. As the furthest points on a diagonal are never
+// further from the other end, the overlap point is on a shortest path.
+func (d *differ) middle(x0, x1, y0, y1 int) (int, int) {
+	n, m := x1-x0, y1-y0
+	delta := n - m
+	maxEdits := (n + m + 1) / 2
+	offset := maxEdits + 1
+	fv, bv := d.fv, d.bv
+	fv[offset+1], bv[offset+1] = 0, 0
+
+	for e := 0; e <= maxEdits; e++ {
+		for k := -e; k <= e; k += 2 {
+			var x int
+			if k == -e || (k != e && fv[offset+k-1] < fv[offset+k+1]) {
+				x = fv[offset+k+1]
+			} else {
+				x = fv[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.xs[x0+x] == d.ys[y0+y] {
+				x++
+				y++
+			}
+			fv[offset+k] = x
+			// With an odd delta, the overlapping backward path is shorter.
+			if c := delta - k; delta%2 != 0 && -e < c && c < e && x+bv[offset+c] >= n {
+				return x0 + x, y0 + y
+			}
+		}
+		for c := -e; c <= e; c += 2 {
+			var x int
+			if c == -e || (c != e && bv[offset+c-1] < bv[offset+c+1]) {
+				x = bv[offset+c+1]
+			} else {
+				x = bv[offset+c-1] + 1
+			}
+			y := x - c
+			for x < n && y < m && d.xs[x1-x-1] == d.ys[y1-y-1] {
+				x++
+				y++
+			}
+			bv[offset+c] = x
+			if k := delta - c; delta%2 == 0 && -e <= k && k <= e && x+fv[offset+k] >= n {
+				return x1 - x, y1 - y
+			}
+		}
+	}
+	panic("unreachable")
+}
+
+// groupChanges reorders each group of adjacent changes,
+// so that deletions precede insertions.
+func groupChanges(edits []lineEdit) []lineEdit {
+	grouped := make([]lineEdit, 0, len(edits))
+	for i := 0; i < len(edits); {
+		if edits[i].kind == editKeep {
+			grouped = append(grouped, edits[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(edits) && edits[j].kind != editKeep {
+			j++
+		}
+		x, y := edits[i].x, edits[i].y
+		var deleted int
+		for _, e := range edits[i:j] {
+			if e.kind == editDelete {
+				grouped = append(grouped, lineEdit{kind: editDelete, x: e.x, y: y})
+				deleted++
+			}
+		}
+		for _, e := range edits[i:j] {
+			if e.kind == editInsert {
+				grouped = append(grouped, lineEdit{kind: editInsert, x: x + deleted, y: e.y})
+			}
+		}
+		i = j
+	}
+	return grouped
+}
+
+// hunk is a group of nearby line edits with their context.
+type hunk struct {
+	edits []lineEdit
+}
+
+// hunks groups edits into hunks with context lines around the changes.
+// Changes separated by at most 2*context unchanged lines
+// are put into the same hunk.
+func hunks(edits []lineEdit, context int) []hunk {
+	if context < 0 {
+		context = 0
+	}
+	var list []hunk
+	start, end := -1, -1
+	for i, e := range edits {
+		if e.kind == editKeep {
+			continue
+		}
+		if start >= 0 && i-end > 2*context+1 {
+			list = append(list, hunk{edits: edits[start:imin(end+context+1, len(edits))]})
+			start = -1
+		}
+		if start < 0 {
+			start = imax(i-context, 0)
+		}
+		end = i
+	}
+	if start >= 0 {
+		list = append(list, hunk{edits: edits[start:imin(end+context+1, len(edits))]})
+	}
+	return list
+}
+
+// write writes the h hunk in the unified diff format.
+func (h hunk) write(buf *strings.Builder, x, y diffText, xlines, ylines []string) {
+	xstart, ystart := -1, -1
+	xcount, ycount := 0, 0
+	for _, e := range h.edits {
+		if e.kind != editInsert {
+			if xstart < 0 {
+				xstart = e.x
+			}
+			xcount++
+		}
+		if e.kind != editDelete {
+			if ystart < 0 {
+				ystart = e.y
+			}
+			ycount++
+		}
+	}
+	// Empty ranges refer to the line before the (empty) range.
+	if xstart < 0 {
+		xstart = h.edits[0].x
+	}
+	if ystart < 0 {
+		ystart = h.edits[0].y
+	}
+	fmt.Fprintf(buf, "@@ -%s +%s @@",
+		hunkRange(x.srcLine(xstart, xcount), xcount),
+		hunkRange(y.srcLine(ystart, ycount), ycount))
+	if x.srcLines != nil || y.srcLines != nil {
+		x.writeLabel(buf, xstart, xcount)
+		y.writeLabel(buf, ystart, ycount)
+	}
+	buf.WriteByte('\n')
+
+	for _, e := range h.edits {
+		var prefix byte
+		var line string
+		switch e.kind {
+		case editKeep:
+			prefix, line = ' ', xlines[e.x]
+		case editDelete:
+			prefix, line = '-', xlines[e.x]
+		case editInsert:
+			prefix, line = '+', ylines[e.y]
+		}
+		buf.WriteByte(prefix)
+		buf.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			buf.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// srcLine returns the 0-based source line index of the i text line.
+// For empty ranges, the i line is the one after the range.
+func (t diffText) srcLine(i, count int) int {
+	switch {
+	case t.srcLines == nil:
+		return i
+	case count != 0:
+		return t.srcLines[i] - 1
+	case i > 0:
+		return t.srcLines[i-1]
+	default:
+		return t.srcLines[0] - 1
+	}
+}
+
+// writeLabel writes the file:line range of the count lines
+// starting at the i text line.
+func (t diffText) writeLabel(buf *strings.Builder, i, count int) {
+	if t.srcLines == nil || count == 0 {
+		return
+	}
+	first, last := t.srcLines[i], t.srcLines[i+count-1]
+	if first == last {
+		fmt.Fprintf(buf, " %s:%d", t.name, first)
+	} else {
+		fmt.Fprintf(buf, " %s:%d-%d", t.name, first, last)
+	}
+}
+
+// hunkRange formats a hunk range of count lines starting
+// at the start line index.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+func imin(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
 
-	// TODO(cristaloleg): replace with a more lightweight diff impl.
-	return cmp.Diff(s1, s2)
+func imax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
 }
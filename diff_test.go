@@ -0,0 +1,391 @@
+package astequal
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestDiff(t *testing.T) {
+	x := strparse.Stmt(`{
+	a()
+	b()
+	c()
+	d()
+	e()
+	f()
+	g()
+	h()
+	i()
+	j()
+}`)
+	y := strparse.Stmt(`{
+	a()
+	B()
+	c()
+	d()
+	e()
+	f()
+	g()
+	h()
+	i()
+	j()
+	k()
+}`)
+
+	want := `--- x
++++ y
+@@ -1,6 +1,6 @@
+ {
+ 	a()
+-	b()
++	B()
+ 	c()
+ 	d()
+ 	e()
+@@ -9,4 +9,5 @@
+ 	h()
+ 	i()
+ 	j()
++	k()
+ }
+`
+	if have := Diff(x, y); have != want {
+		t.Errorf("Diff:\nhave:\n%s\nwant:\n%s", have, want)
+	}
+
+	want = `--- x
++++ y
+@@ -3 +3 @@
+-	b()
++	B()
+@@ -11,0 +12 @@
++	k()
+`
+	if have := New(DiffContext(0)).Diff(x, y); have != want {
+		t.Errorf("Diff with no context:\nhave:\n%s\nwant:\n%s", have, want)
+	}
+
+	if have := Diff(x, x); have != "" {
+		t.Errorf("Diff of equal nodes:\nhave:\n%s\nwant empty diff", have)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		x    string
+		y    string
+		want string
+	}{
+		{"", "a\n", "@@ -0,0 +1 @@\n+a\n"},
+		{"a\n", "", "@@ -1 +0,0 @@\n-a\n"},
+		{"a\nb\n", "a\nc\n", "@@ -1,2 +1,2 @@\n a\n-b\n+c\n"},
+		{"a\n", "a", "@@ -1 +1 @@\n-a\n+a\n\\ No newline at end of file\n"},
+	}
+	for _, test := range tests {
+		want := "--- x\n+++ y\n" + test.want
+		x, y := diffText{name: "x", text: test.x}, diffText{name: "y", text: test.y}
+		if have := unifiedDiff(x, y, 3); have != want {
+			t.Errorf("unifiedDiff(%q, %q):\nhave:\n%s\nwant:\n%s",
+				test.x, test.y, have, want)
+		}
+	}
+}
+
+func TestUnifiedDiffApply(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randomText := func() string {
+		var lines []string
+		for i := r.Intn(30); i > 0; i-- {
+			lines = append(lines, strconv.Itoa(r.Intn(5))+"\n")
+		}
+		return strings.Join(lines, "")
+	}
+
+	for i := 0; i < 1000; i++ {
+		x, y := randomText(), randomText()
+		context := r.Intn(4)
+		diff := unifiedDiff(diffText{name: "x", text: x}, diffText{name: "y", text: y}, context)
+		have, err := applyDiff(x, diff)
+		if err != nil {
+			t.Fatalf("apply diff of %q and %q: %v\n%s", x, y, err, diff)
+		}
+		if have != y {
+			t.Fatalf("apply diff of %q and %q:\nhave: %q\ndiff:\n%s", x, y, have, diff)
+		}
+
+		// The edit script must be the shortest one.
+		xlines, ylines := splitLines(x), splitLines(y)
+		changes := 0
+		for _, e := range diffLines(xlines, ylines) {
+			if e.kind != editKeep {
+				changes++
+			}
+		}
+		if want := len(xlines) + len(ylines) - 2*lcsLen(xlines, ylines); changes != want {
+			t.Fatalf("diff of %q and %q: have %d changes, want %d", x, y, changes, want)
+		}
+	}
+}
+
+func TestDiffLinesMemory(t *testing.T) {
+	const n = 4000
+	xs, ys := make([]string, n), make([]string, n)
+	for i := range xs {
+		xs[i] = "x" + strconv.Itoa(i) + "\n"
+		ys[i] = "y" + strconv.Itoa(i) + "\n"
+	}
+
+	// The edit script of completely different texts is the longest one,
+	// but the memory must still be linear.
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	edits := diffLines(xs, ys)
+	runtime.ReadMemStats(&after)
+	if len(edits) != 2*n {
+		t.Fatalf("have %d edits, want %d", len(edits), 2*n)
+	}
+	if allocs, max := after.TotalAlloc-before.TotalAlloc, uint64(4<<20); allocs > max {
+		t.Errorf("diffLines allocated %d bytes, want at most %d", allocs, max)
+	}
+}
+
+// applyDiff applies the unified diff to x.
+func applyDiff(x, diff string) (string, error) {
+	if diff == "" {
+		return x, nil
+	}
+	xlines := splitLines(x)
+	var out []string
+	next := 0
+	lines := splitLines(diff)[2:]
+	for len(lines) != 0 {
+		var xstart, xcount int
+		header := lines[0]
+		lines = lines[1:]
+		xrange := strings.Fields(header)[1][1:]
+		if i := strings.IndexByte(xrange, ','); i >= 0 {
+			xstart, _ = strconv.Atoi(xrange[:i])
+			xcount, _ = strconv.Atoi(xrange[i+1:])
+		} else {
+			xstart, _ = strconv.Atoi(xrange)
+			xcount = 1
+		}
+		if xcount != 0 {
+			xstart--
+		}
+		if xstart < next {
+			return "", fmt.Errorf("overlapping hunk %q", header)
+		}
+		out = append(out, xlines[next:xstart]...)
+		next = xstart
+		for len(lines) != 0 && !strings.HasPrefix(lines[0], "@@") {
+			line := lines[0]
+			lines = lines[1:]
+			switch line[0] {
+			case ' ', '-':
+				if xlines[next] != line[1:] {
+					return "", fmt.Errorf("line %d mismatch: %q", next+1, line)
+				}
+				if line[0] == ' ' {
+					out = append(out, line[1:])
+				}
+				next++
+			case '+':
+				out = append(out, line[1:])
+			}
+		}
+	}
+	out = append(out, xlines[next:]...)
+	return strings.Join(out, ""), nil
+}
+
+func lcsLen(xs, ys []string) int {
+	dp := make([][]int, len(xs)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(ys)+1)
+	}
+	for i := len(xs) - 1; i >= 0; i-- {
+		for j := len(ys) - 1; j >= 0; j-- {
+			switch {
+			case xs[i] == ys[j]:
+				dp[i][j] = dp[i+1][j+1] + 1
+			case dp[i+1][j] > dp[i][j+1]:
+				dp[i][j] = dp[i+1][j]
+			default:
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+	return dp[0][0]
+}
+
+func TestDiffFileSets(t *testing.T) {
+	const xsrc = `package foo
+
+import "fmt"
+
+// F prints x.
+func F(x int) {
+	if x > 0 {
+		fmt.Println(x)
+	}
+}
+`
+	const ysrc = `package foo
+
+import "fmt"
+
+var v = 1
+
+// F prints x.
+func F(x int) {
+	if x > 0 {
+		fmt.Println(x, v)
+	}
+}
+`
+	fset := token.NewFileSet()
+	xf, err := parser.ParseFile(fset, "x.go", xsrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yf, err := parser.ParseFile(fset, "y.go", ysrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y := xf.Decls[1], yf.Decls[2]
+
+	want := `--- x.go
++++ y.go
+@@ -7,3 +9,3 @@ x.go:7-9 y.go:9-11
+ 	if x > 0 {
+-		fmt.Println(x)
++		fmt.Println(x, v)
+ 	}
+`
+	cmp := New(WithFileSets(fset, nil), DiffContext(1))
+	if have := cmp.Diff(x, y); have != want {
+		t.Errorf("Diff:\nhave:\n%s\nwant:\n%s", have, want)
+	}
+
+	// The whole file lines are the same as the source ones.
+	want = `--- x.go
++++ y.go
+@@ -4,0 +5,2 @@ y.go:5-6
++var v = 1
++
+@@ -8 +10 @@ x.go:8 y.go:10
+-		fmt.Println(x)
++		fmt.Println(x, v)
+`
+	cmp = New(WithFileSets(fset, fset), DiffContext(0))
+	if have := cmp.Diff(xf, yf); have != want {
+		t.Errorf("Diff:\nhave:\n%s\nwant:\n%s", have, want)
+	}
+}
+
+func TestDiffErr(t *testing.T) {
+	ident := strparse.Expr(`x`)
+
+	tests := []struct {
+		name string
+		x    ast.Node
+		y    ast.Node
+		diff string
+		err  error
+	}{
+		{
+			name: "nil nodes",
+			x:    (*ast.Ident)(nil),
+			y:    (*ast.Ident)(nil),
+			diff: ``,
+		},
+		{
+			name: "nil x",
+			x:    nil,
+			y:    ident,
+			diff: "--- x\n+++ y\n@@ -0,0 +1 @@\n+x\n",
+		},
+		{
+			name: "bad nodes",
+			x:    &ast.CallExpr{Fun: &ast.BadExpr{}},
+			y:    &ast.CallExpr{Fun: &ast.BadExpr{}},
+			diff: "--- x\n+++ y\n@@ -1,2 +1,2 @@\n BadExpr()\n" +
+				"-// astequal: x contains bad node (BadExpr)\n" +
+				"+// astequal: y contains bad node (BadExpr)\n",
+			err: ErrBadNode,
+		},
+		{
+			name: "unsupported node",
+			x:    ident,
+			y:    &ast.Field{Type: ident},
+			diff: "--- x\n+++ y\n@@ -1 +1 @@\n-x\n" +
+				"+// astequal: y: unprintable node: go/printer: unsupported node type *ast.Field\n",
+			err: ErrUnprintable,
+		},
+		{
+			name: "malformed node",
+			x:    &ast.ExprStmt{},
+			y:    &ast.ExprStmt{X: ident},
+			err:  ErrUnprintable,
+		},
+		{
+			name: "illegal operator",
+			x:    &ast.BinaryExpr{X: ident, Op: token.ILLEGAL, Y: ident},
+			y:    &ast.BinaryExpr{X: ident, Op: token.ADD, Y: ident},
+			diff: "--- x\n+++ y\n@@ -1 +1 @@\n" +
+				"-// astequal: x: unprintable node: BinaryExpr with ILLEGAL token\n" +
+				"+x + x\n",
+			err: ErrUnprintable,
+		},
+		{
+			name: "empty identifier",
+			x:    &ast.Ident{Name: ""},
+			y:    ident,
+			diff: "--- x\n+++ y\n@@ -1 +1 @@\n" +
+				"-// astequal: x: unprintable node: empty Ident\n" +
+				"+x\n",
+			err: ErrUnprintable,
+		},
+		{
+			name: "range without key",
+			x:    &ast.RangeStmt{X: ident, Body: &ast.BlockStmt{}},
+			y:    &ast.RangeStmt{X: ident, Body: &ast.BlockStmt{}},
+			diff: ``,
+		},
+		{
+			name: "implicit empty statement",
+			x:    &ast.BlockStmt{List: []ast.Stmt{&ast.EmptyStmt{Implicit: true}}},
+			y:    &ast.BlockStmt{List: []ast.Stmt{&ast.EmptyStmt{Implicit: false}}},
+			diff: "--- x\n+++ y\n@@ -1,2 +1,3 @@\n {\n }\n" +
+				"+// astequal: x and y: unequal nodes printed identically\n",
+			err: ErrPrintedIdentically,
+		},
+	}
+
+	for _, test := range tests {
+		diff, err := DiffErr(test.x, test.y)
+		if !errors.Is(err, test.err) || (err == nil) != (test.err == nil) {
+			t.Errorf("%s: have error %v, want %v", test.name, err, test.err)
+		}
+		if test.diff != "" && diff != test.diff {
+			t.Errorf("%s:\nhave:\n%s\nwant:\n%s", test.name, diff, test.diff)
+		}
+		if (diff == "") != Node(test.x, test.y) {
+			t.Errorf("%s: diff must be empty iff the nodes are equal", test.name)
+		}
+		if have := Diff(test.x, test.y); have != diff {
+			t.Errorf("%s: Diff and DiffErr disagree:\n%s\n%s", test.name, have, diff)
+		}
+	}
+}
@@ -0,0 +1,813 @@
+package astequal
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"strings"
+)
+
+// Mismatch describes the first difference between two AST nodes.
+type Mismatch struct {
+	// Path is a field path from the compared nodes to the differing nodes,
+	// like "FuncDecl.Body.List[2].X.Args[0]".
+	// It starts with the compared nodes type name.
+	Path string
+
+	// X and Y are the differing (sub)nodes.
+	//
+	// For LengthMismatch they are the nodes that hold the lists.
+	// One of them is nil for NilMismatch.
+	X, Y ast.Node
+
+	// Reason describes the difference.
+	Reason Reason
+
+	// rest are the other differences found in the ExplainAll mode.
+	rest []*Mismatch
+}
+
+// String returns a short mismatch description.
+func (m *Mismatch) String() string {
+	return m.Path + ": " + m.Reason.String()
+}
+
+// Reason describes the way two AST nodes differ.
+type Reason int
+
+// Mismatch reasons.
+const (
+	// OtherMismatch is reported for any difference that is not
+	// described by a more specific reason, like channel directions
+	// or ellipsis presence in calls.
+	OtherMismatch Reason = iota
+
+	// NilMismatch is reported when only one of the nodes is nil.
+	NilMismatch
+
+	// KindMismatch is reported for nodes of different types.
+	KindMismatch
+
+	// NameMismatch is reported for identifiers with different names,
+	// or bound by different declarations in the AlphaEquivalence mode.
+	NameMismatch
+
+	// ValueMismatch is reported for basic literals, tags and comments
+	// with different values.
+	ValueMismatch
+
+	// OperatorMismatch is reported for nodes with different operators
+	// or tokens, like x+y and x-y or x=y and x:=y.
+	OperatorMismatch
+
+	// LengthMismatch is reported for node lists of different length.
+	LengthMismatch
+
+	// BadNode is reported for ast.BadExpr, ast.BadStmt and ast.BadDecl,
+	// as they are never equal.
+	BadNode
+)
+
+var reasonNames = [...]string{
+	OtherMismatch:    "mismatch",
+	NilMismatch:      "nil mismatch",
+	KindMismatch:     "kind mismatch",
+	NameMismatch:     "name mismatch",
+	ValueMismatch:    "value mismatch",
+	OperatorMismatch: "operator mismatch",
+	LengthMismatch:   "length mismatch",
+	BadNode:          "bad node",
+}
+
+// String returns a human-readable reason name.
+func (r Reason) String() string {
+	if r < 0 || int(r) >= len(reasonNames) {
+		return fmt.Sprintf("Reason(%d)", int(r))
+	}
+	return reasonNames[r]
+}
+
+// Explain reports where and why two AST nodes differ.
+//
+// It returns nil if x and y are equal in the Node function sense.
+// Otherwise, the first difference in the fields order is reported.
+func Explain(x, y ast.Node) *Mismatch {
+	var c comparer
+	return c.explain(x, y)
+}
+
+// Explain reports where and why two AST nodes differ.
+//
+// With IgnoreOrder, the unordered list elements that have equal
+// counterparts are skipped, and the rest of them are explained
+// in their order.
+// With IgnoreDeclGrouping, the statement and declaration list indexes
+// in the paths refer to the lists of ungrouped declarations.
+//
+// See also: Explain function.
+func (cmp *Comparer) Explain(x, y ast.Node) *Mismatch {
+	c := cmp.comparer(x, y)
+	return c.explain(x, y)
+}
+
+// ExplainAll is like Explain, but reports all differences
+// between x and y instead of the first one.
+//
+// Children of the differing nodes are explained independently,
+// except for the nodes that differ in length or kind: their
+// children are not compared.
+func ExplainAll(x, y ast.Node) []*Mismatch {
+	var cmp *Comparer
+	return cmp.ExplainAll(x, y)
+}
+
+// ExplainAll is like ExplainAll function, but uses the Comparer settings.
+//
+// See also: Comparer.Explain.
+func (cmp *Comparer) ExplainAll(x, y ast.Node) []*Mismatch {
+	c := cmp.comparer(x, y)
+	c.explainAll = true
+	m := c.explain(x, y)
+	if m == nil {
+		return nil
+	}
+	return m.flatten()
+}
+
+func (c *comparer) explain(x, y ast.Node) *Mismatch {
+	if c.astNodeEq(x, y) {
+		return nil
+	}
+	if c.ignoreParens {
+		x, y = unparenNode(x), unparenNode(y)
+	}
+	m := c.explainNode(x, y)
+	root := x
+	if isNilNode(root) {
+		root = y
+	}
+	m.prefix(nodeTypeName(root))
+	return m
+}
+
+// prefix adds the p prefix to m paths.
+func (m *Mismatch) prefix(p string) {
+	m.Path = p + m.Path
+	for _, r := range m.rest {
+		r.Path = p + r.Path
+	}
+}
+
+// flatten returns m and the rest of mismatches found with it.
+func (m *Mismatch) flatten() []*Mismatch {
+	list := append([]*Mismatch{m}, m.rest...)
+	m.rest = nil
+	return list
+}
+
+// explainNode finds the first difference between unequal x and y.
+//
+// The returned mismatch path is relative to x and y.
+func (c *comparer) explainNode(x, y ast.Node) *Mismatch {
+	if c.ignoreParens {
+		x, y = unparenNode(x), unparenNode(y)
+	}
+	var m *Mismatch
+	if c.alpha {
+		m = c.explainBound(x, y)
+	} else {
+		m = c.explainFields(x, y)
+	}
+	if m != nil {
+		return m
+	}
+	// All children are equal on their own, so x and y differ
+	// as a whole, like for a custom comparison result.
+	return c.mismatch(x, y, OtherMismatch)
+}
+
+// explainFields is like explainNode, but returns nil
+// if x and y children have no differences.
+func (c *comparer) explainFields(x, y ast.Node) *Mismatch {
+	switch {
+	case isNilNode(x) || isNilNode(y):
+		if isNilNode(x) == isNilNode(y) {
+			// Nil interface vs typed nil pointer.
+			return c.mismatch(x, y, KindMismatch)
+		}
+		return c.mismatch(x, y, NilMismatch)
+	case reflect.TypeOf(x) != reflect.TypeOf(y):
+		return c.mismatch(x, y, KindMismatch)
+	}
+
+	switch x := x.(type) {
+	case *ast.BadExpr, *ast.BadStmt, *ast.BadDecl:
+		return c.mismatch(x, y, BadNode)
+
+	case *ast.Ident:
+		y := y.(*ast.Ident)
+		if x.Name != y.Name {
+			return c.mismatch(x, y, NameMismatch)
+		}
+	case *ast.BasicLit:
+		y := y.(*ast.BasicLit)
+		if x.Kind != y.Kind || x.Value != y.Value {
+			return c.mismatch(x, y, ValueMismatch)
+		}
+	case *ast.FuncLit:
+		y := y.(*ast.FuncLit)
+		return c.explainEdges(x, y,
+			edge("Type", x.Type, y.Type),
+			edge("Body", x.Body, y.Body))
+	case *ast.CompositeLit:
+		y := y.(*ast.CompositeLit)
+		if c.elideTypes {
+			return c.explainCompositeLit(x, y, nil, nil, false)
+		}
+		elts := listEdge("Elts", exprNodes(x.Elts), exprNodes(y.Elts))
+		if c.ignoreOrder && unorderedLit(c.xinfo, x) && unorderedLit(c.yinfo, y) {
+			elts.unordered = true
+		}
+		return c.explainEdges(x, y,
+			edge("Type", x.Type, y.Type),
+			elts)
+	case *ast.ParenExpr:
+		y := y.(*ast.ParenExpr)
+		return c.explainEdges(x, y,
+			edge("X", x.X, y.X))
+	case *ast.SelectorExpr:
+		y := y.(*ast.SelectorExpr)
+		return c.explainEdges(x, y,
+			edge("X", x.X, y.X),
+			edge("Sel", x.Sel, y.Sel))
+	case *ast.IndexExpr:
+		y := y.(*ast.IndexExpr)
+		return c.explainEdges(x, y,
+			edge("X", x.X, y.X),
+			edge("Index", x.Index, y.Index))
+	case *ast.IndexListExpr:
+		y := y.(*ast.IndexListExpr)
+		return c.explainEdges(x, y,
+			edge("X", x.X, y.X),
+			listEdge("Indices", exprNodes(x.Indices), exprNodes(y.Indices)))
+	case *ast.SliceExpr:
+		y := y.(*ast.SliceExpr)
+		return c.explainEdges(x, y,
+			edge("X", x.X, y.X),
+			edge("Low", x.Low, y.Low),
+			edge("High", x.High, y.High),
+			edge("Max", x.Max, y.Max))
+	case *ast.TypeAssertExpr:
+		y := y.(*ast.TypeAssertExpr)
+		return c.explainEdges(x, y,
+			edge("X", x.X, y.X),
+			edge("Type", x.Type, y.Type))
+	case *ast.CallExpr:
+		y := y.(*ast.CallExpr)
+		if m := c.explainEdges(x, y,
+			edge("Fun", x.Fun, y.Fun),
+			listEdge("Args", exprNodes(x.Args), exprNodes(y.Args))); m != nil {
+			return m
+		}
+	case *ast.StarExpr:
+		y := y.(*ast.StarExpr)
+		return c.explainEdges(x, y,
+			edge("X", x.X, y.X))
+	case *ast.UnaryExpr:
+		y := y.(*ast.UnaryExpr)
+		if x.Op != y.Op {
+			return c.mismatch(x, y, OperatorMismatch)
+		}
+		return c.explainEdges(x, y,
+			edge("X", x.X, y.X))
+	case *ast.BinaryExpr:
+		y := y.(*ast.BinaryExpr)
+		if x.Op != y.Op {
+			return c.mismatch(x, y, OperatorMismatch)
+		}
+		if c.commutedExplained(x, y) {
+			return c.explainEdges(x, y,
+				edge("X", x.X, y.Y),
+				edge("Y", x.Y, y.X))
+		}
+		return c.explainEdges(x, y,
+			edge("X", x.X, y.X),
+			edge("Y", x.Y, y.Y))
+	case *ast.KeyValueExpr:
+		y := y.(*ast.KeyValueExpr)
+		return c.explainEdges(x, y,
+			edge("Key", x.Key, y.Key),
+			edge("Value", x.Value, y.Value))
+	case *ast.ArrayType:
+		y := y.(*ast.ArrayType)
+		return c.explainEdges(x, y,
+			edge("Len", x.Len, y.Len),
+			edge("Elt", x.Elt, y.Elt))
+	case *ast.StructType:
+		y := y.(*ast.StructType)
+		return c.explainEdges(x, y,
+			edge("Fields", x.Fields, y.Fields))
+	case *ast.FuncType:
+		y := y.(*ast.FuncType)
+		return c.explainEdges(x, y,
+			edge("Params", x.Params, y.Params),
+			edge("Results", x.Results, y.Results),
+			edge("TypeParams", x.TypeParams, y.TypeParams))
+	case *ast.InterfaceType:
+		y := y.(*ast.InterfaceType)
+		if c.ignoreOrder && x.Methods != nil && y.Methods != nil {
+			return c.explainEdges(x, y,
+				unorderedEdge("Methods.List", fieldNodes(x.Methods.List), fieldNodes(y.Methods.List)))
+		}
+		return c.explainEdges(x, y,
+			edge("Methods", x.Methods, y.Methods))
+	case *ast.MapType:
+		y := y.(*ast.MapType)
+		return c.explainEdges(x, y,
+			edge("Key", x.Key, y.Key),
+			edge("Value", x.Value, y.Value))
+	case *ast.ChanType:
+		y := y.(*ast.ChanType)
+		if x.Dir != y.Dir {
+			return c.mismatch(x, y, OtherMismatch)
+		}
+		return c.explainEdges(x, y,
+			edge("Value", x.Value, y.Value))
+	case *ast.Ellipsis:
+		y := y.(*ast.Ellipsis)
+		return c.explainEdges(x, y,
+			edge("Elt", x.Elt, y.Elt))
+
+	case *ast.ExprStmt:
+		y := y.(*ast.ExprStmt)
+		return c.explainEdges(x, y,
+			edge("X", x.X, y.X))
+	case *ast.SendStmt:
+		y := y.(*ast.SendStmt)
+		return c.explainEdges(x, y,
+			edge("Chan", x.Chan, y.Chan),
+			edge("Value", x.Value, y.Value))
+	case *ast.IncDecStmt:
+		y := y.(*ast.IncDecStmt)
+		if x.Tok != y.Tok {
+			return c.mismatch(x, y, OperatorMismatch)
+		}
+		return c.explainEdges(x, y,
+			edge("X", x.X, y.X))
+	case *ast.AssignStmt:
+		y := y.(*ast.AssignStmt)
+		if x.Tok != y.Tok {
+			return c.mismatch(x, y, OperatorMismatch)
+		}
+		return c.explainEdges(x, y,
+			listEdge("Lhs", exprNodes(x.Lhs), exprNodes(y.Lhs)),
+			listEdge("Rhs", exprNodes(x.Rhs), exprNodes(y.Rhs)))
+	case *ast.GoStmt:
+		y := y.(*ast.GoStmt)
+		return c.explainEdges(x, y,
+			edge("Call", x.Call, y.Call))
+	case *ast.DeferStmt:
+		y := y.(*ast.DeferStmt)
+		return c.explainEdges(x, y,
+			edge("Call", x.Call, y.Call))
+	case *ast.ReturnStmt:
+		y := y.(*ast.ReturnStmt)
+		return c.explainEdges(x, y,
+			listEdge("Results", exprNodes(x.Results), exprNodes(y.Results)))
+	case *ast.BranchStmt:
+		y := y.(*ast.BranchStmt)
+		if x.Tok != y.Tok {
+			return c.mismatch(x, y, OperatorMismatch)
+		}
+		return c.explainEdges(x, y,
+			edge("Label", x.Label, y.Label))
+	case *ast.BlockStmt:
+		y := y.(*ast.BlockStmt)
+		return c.explainEdges(x, y,
+			c.stmtsEdge("List", x.List, y.List))
+	case *ast.IfStmt:
+		y := y.(*ast.IfStmt)
+		return c.explainEdges(x, y,
+			edge("Init", x.Init, y.Init),
+			edge("Cond", x.Cond, y.Cond),
+			edge("Body", x.Body, y.Body),
+			edge("Else", x.Else, y.Else))
+	case *ast.CaseClause:
+		y := y.(*ast.CaseClause)
+		return c.explainEdges(x, y,
+			listEdge("List", exprNodes(x.List), exprNodes(y.List)),
+			c.stmtsEdge("Body", x.Body, y.Body))
+	case *ast.SwitchStmt:
+		y := y.(*ast.SwitchStmt)
+		if c.ignoreOrder && unorderedSwitch(c.xinfo, x) && unorderedSwitch(c.yinfo, y) {
+			return c.explainEdges(x, y,
+				edge("Init", x.Init, y.Init),
+				edge("Tag", x.Tag, y.Tag),
+				unorderedEdge("Body.List", stmtNodes(x.Body.List), stmtNodes(y.Body.List)))
+		}
+		return c.explainEdges(x, y,
+			edge("Init", x.Init, y.Init),
+			edge("Tag", x.Tag, y.Tag),
+			edge("Body", x.Body, y.Body))
+	case *ast.TypeSwitchStmt:
+		y := y.(*ast.TypeSwitchStmt)
+		if c.ignoreOrder && unorderedTypeSwitch(c.xinfo, x) && unorderedTypeSwitch(c.yinfo, y) {
+			return c.explainEdges(x, y,
+				edge("Init", x.Init, y.Init),
+				edge("Assign", x.Assign, y.Assign),
+				unorderedEdge("Body.List", stmtNodes(x.Body.List), stmtNodes(y.Body.List)))
+		}
+		return c.explainEdges(x, y,
+			edge("Init", x.Init, y.Init),
+			edge("Assign", x.Assign, y.Assign),
+			edge("Body", x.Body, y.Body))
+	case *ast.CommClause:
+		y := y.(*ast.CommClause)
+		return c.explainEdges(x, y,
+			edge("Comm", x.Comm, y.Comm),
+			c.stmtsEdge("Body", x.Body, y.Body))
+	case *ast.SelectStmt:
+		y := y.(*ast.SelectStmt)
+		return c.explainEdges(x, y,
+			edge("Body", x.Body, y.Body))
+	case *ast.ForStmt:
+		y := y.(*ast.ForStmt)
+		return c.explainEdges(x, y,
+			edge("Init", x.Init, y.Init),
+			edge("Cond", x.Cond, y.Cond),
+			edge("Post", x.Post, y.Post),
+			edge("Body", x.Body, y.Body))
+	case *ast.RangeStmt:
+		y := y.(*ast.RangeStmt)
+		if x.Tok != y.Tok {
+			return c.mismatch(x, y, OperatorMismatch)
+		}
+		return c.explainEdges(x, y,
+			edge("Key", x.Key, y.Key),
+			edge("Value", x.Value, y.Value),
+			edge("X", x.X, y.X),
+			edge("Body", x.Body, y.Body))
+	case *ast.DeclStmt:
+		y := y.(*ast.DeclStmt)
+		return c.explainEdges(x, y,
+			edge("Decl", x.Decl, y.Decl))
+	case *ast.LabeledStmt:
+		y := y.(*ast.LabeledStmt)
+		return c.explainEdges(x, y,
+			edge("Label", x.Label, y.Label),
+			edge("Stmt", x.Stmt, y.Stmt))
+
+	case *ast.GenDecl:
+		y := y.(*ast.GenDecl)
+		if c.ignoreOrder && x.Tok == token.IMPORT && y.Tok == token.IMPORT {
+			return c.explainEdges(x, y,
+				unorderedEdge("Specs", specNodes(x.Specs), specNodes(y.Specs)),
+				c.commentsEdge("Doc", x.Doc, y.Doc))
+		}
+		if x.Tok != y.Tok {
+			return c.mismatch(x, y, OperatorMismatch)
+		}
+		return c.explainEdges(x, y,
+			listEdge("Specs", specNodes(x.Specs), specNodes(y.Specs)),
+			c.commentsEdge("Doc", x.Doc, y.Doc))
+	case *ast.FuncDecl:
+		y := y.(*ast.FuncDecl)
+		return c.explainEdges(x, y,
+			edge("Recv", x.Recv, y.Recv),
+			edge("Name", x.Name, y.Name),
+			edge("Type", x.Type, y.Type),
+			edge("Body", x.Body, y.Body),
+			c.commentsEdge("Doc", x.Doc, y.Doc))
+
+	case *ast.ImportSpec:
+		y := y.(*ast.ImportSpec)
+		return c.explainEdges(x, y,
+			edge("Name", x.Name, y.Name),
+			edge("Path", x.Path, y.Path),
+			c.commentsEdge("Doc", x.Doc, y.Doc),
+			c.commentsEdge("Comment", x.Comment, y.Comment))
+	case *ast.TypeSpec:
+		y := y.(*ast.TypeSpec)
+		return c.explainEdges(x, y,
+			edge("Name", x.Name, y.Name),
+			edge("Type", x.Type, y.Type),
+			edge("TypeParams", x.TypeParams, y.TypeParams),
+			c.commentsEdge("Doc", x.Doc, y.Doc),
+			c.commentsEdge("Comment", x.Comment, y.Comment))
+	case *ast.ValueSpec:
+		y := y.(*ast.ValueSpec)
+		return c.explainEdges(x, y,
+			listEdge("Names", identNodes(x.Names), identNodes(y.Names)),
+			edge("Type", x.Type, y.Type),
+			listEdge("Values", exprNodes(x.Values), exprNodes(y.Values)),
+			c.commentsEdge("Doc", x.Doc, y.Doc),
+			c.commentsEdge("Comment", x.Comment, y.Comment))
+
+	case *ast.Field:
+		y := y.(*ast.Field)
+		if m := c.explainEdges(x, y,
+			listEdge("Names", identNodes(x.Names), identNodes(y.Names)),
+			edge("Type", x.Type, y.Type)); m != nil {
+			return m
+		}
+		if !c.tagsEq(x.Tag, y.Tag) {
+			m := c.mismatch(x.Tag, y.Tag, ValueMismatch)
+			m.Path = ".Tag"
+			return m
+		}
+		return c.explainEdges(x, y,
+			c.commentsEdge("Doc", x.Doc, y.Doc),
+			c.commentsEdge("Comment", x.Comment, y.Comment))
+	case *ast.FieldList:
+		y := y.(*ast.FieldList)
+		return c.explainEdges(x, y,
+			listEdge("List", fieldNodes(x.List), fieldNodes(y.List)))
+
+	case *ast.File:
+		y := y.(*ast.File)
+		if m := c.explainEdges(x, y,
+			edge("Name", x.Name, y.Name)); m != nil {
+			return m
+		}
+		if buildConstraint(x) != buildConstraint(y) {
+			return c.mismatch(x, y, OtherMismatch)
+		}
+		decls := c.declsEdge("Decls", x.Decls, y.Decls)
+		var imports explainEdge
+		if c.ignoreOrder {
+			// Imports are unordered, the rest of declarations are not.
+			n, m := importDeclsLen(x.Decls), importDeclsLen(y.Decls)
+			imports = c.declsEdge("Decls", x.Decls[:n], y.Decls[:m])
+			imports.unordered = true
+			decls = c.declsEdge("Decls", x.Decls[n:], y.Decls[m:]).startingAt(len(imports.xs))
+		}
+		if m := c.explainEdges(x, y,
+			imports,
+			decls,
+			c.commentsEdge("Doc", x.Doc, y.Doc)); m != nil {
+			return m
+		}
+		if c.comments {
+			return c.explainEdges(x, y,
+				listEdge("Comments", commentGroupNodes(freeComments(x)), commentGroupNodes(freeComments(y))))
+		}
+
+	case *ast.CommentGroup:
+		y := y.(*ast.CommentGroup)
+		return c.explainEdges(x, y,
+			listEdge("List", commentNodes(x.List), commentNodes(y.List)))
+	case *ast.Comment:
+		y := y.(*ast.Comment)
+		if x.Text != y.Text {
+			return c.mismatch(x, y, ValueMismatch)
+		}
+	}
+
+	// Nodes differ in something that is not described above,
+	// like a call ellipsis or a custom comparison result.
+	return c.mismatch(x, y, OtherMismatch)
+}
+
+// explainEdge is a named child (or a list of children) of two compared nodes.
+type explainEdge struct {
+	name string
+
+	list bool
+	x, y ast.Node
+	xs   []ast.Node
+	ys   []ast.Node
+
+	// unordered makes the list elements paired the way unorderedEq
+	// does, so only the unpaired ones are explained.
+	unordered bool
+
+	// offset is the index of the first list element in the paths.
+	offset int
+
+	// xiotas and yiotas are the iota values of the ungrouped
+	// declarations list elements, see iotaEq.
+	xiotas []int
+	yiotas []int
+
+	// explain, if not nil, explains the x and y nodes instead of
+	// explainNode. It returns nil if they are equal.
+	explain func() *Mismatch
+}
+
+func edge(name string, x, y ast.Node) explainEdge {
+	return explainEdge{name: name, x: x, y: y}
+}
+
+func listEdge(name string, xs, ys []ast.Node) explainEdge {
+	return explainEdge{name: name, list: true, xs: xs, ys: ys}
+}
+
+func unorderedEdge(name string, xs, ys []ast.Node) explainEdge {
+	return explainEdge{name: name, list: true, xs: xs, ys: ys, unordered: true}
+}
+
+// startingAt returns the e list edge with the elements paths
+// starting at the i index.
+func (e explainEdge) startingAt(i int) explainEdge {
+	e.offset = i
+	return e
+}
+
+// commentsEdge returns a comment group edge that is only
+// taken into account in comments-aware mode.
+func (c *comparer) commentsEdge(name string, x, y *ast.CommentGroup) explainEdge {
+	if !c.comments {
+		return explainEdge{}
+	}
+	return edge(name, x, y)
+}
+
+// explainEdges finds the first unequal edge of x and y.
+// Returns nil if all edges are equal.
+//
+// In the ExplainAll mode, all unequal edges are explained,
+// and the first mismatch holds the rest of them.
+func (c *comparer) explainEdges(x, y ast.Node, edges ...explainEdge) *Mismatch {
+	var found []*Mismatch
+	add := func(m *Mismatch, prefix string) bool {
+		m.prefix(prefix)
+		found = append(found, m.flatten()...)
+		return c.explainAll
+	}
+
+	for _, e := range edges {
+		switch {
+		case e.name == "":
+			continue
+
+		case e.list:
+			if len(e.xs) != len(e.ys) {
+				if !add(c.mismatch(x, y, LengthMismatch), "."+e.name) {
+					return found[0]
+				}
+				continue
+			}
+			xs, ys := c.explainedPairs(e)
+			for k, i := range xs {
+				j := ys[k]
+				var m *Mismatch
+				switch {
+				case e.xiotas != nil && !iotaEq(nodeDecl(e.xs[i]), nodeDecl(e.ys[j]), e.xiotas[i], e.yiotas[j]):
+					m = c.mismatch(e.xs[i], e.ys[j], OtherMismatch)
+				case c.astNodeEq(e.xs[i], e.ys[j]):
+					continue
+				default:
+					m = c.explainNode(e.xs[i], e.ys[j])
+				}
+				if !add(m, fmt.Sprintf(".%s[%d]", e.name, e.offset+i)) {
+					return found[0]
+				}
+			}
+
+		case e.explain != nil:
+			m := e.explain()
+			if m == nil {
+				continue
+			}
+			if !add(m, "."+e.name) {
+				return found[0]
+			}
+
+		default:
+			if c.astNodeEq(e.x, e.y) {
+				continue
+			}
+			m := c.explainNode(e.x, e.y)
+			if !add(m, "."+e.name) {
+				return found[0]
+			}
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	if len(found) > 1 {
+		found[0].rest = found[1:]
+	}
+	return found[0]
+}
+
+// explainedPairs returns the indexes of the e list elements pairs
+// to explain. For unordered lists, the elements that are paired
+// by unorderedEq are skipped, and the rest are paired in order.
+func (c *comparer) explainedPairs(e explainEdge) (xs, ys []int) {
+	n := len(e.xs)
+	if !e.unordered {
+		xs = make([]int, n)
+		for i := range xs {
+			xs[i] = i
+		}
+		return xs, xs
+	}
+	xs, used := c.pairGreedily(n, func(i, j int) bool {
+		return c.astNodeEq(e.xs[i], e.ys[j])
+	}, true)
+	for j := range used {
+		if !used[j] {
+			ys = append(ys, j)
+		}
+	}
+	return xs, ys
+}
+
+func (c *comparer) mismatch(x, y ast.Node, reason Reason) *Mismatch {
+	return &Mismatch{X: x, Y: y, Reason: reason}
+}
+
+// unparenNode returns n with any enclosing parentheses stripped,
+// if it's an expression.
+func unparenNode(n ast.Node) ast.Node {
+	if e, ok := n.(ast.Expr); ok {
+		return unparen(e)
+	}
+	return n
+}
+
+// isNilNode reports whether n is nil or a nil pointer.
+func isNilNode(n ast.Node) bool {
+	if n == nil {
+		return true
+	}
+	v := reflect.ValueOf(n)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// nodeTypeName returns n type name without the package qualifier.
+func nodeTypeName(n ast.Node) string {
+	if n == nil {
+		return "nil"
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast.")
+}
+
+// Adapters from the typed node lists to the []ast.Node.
+
+func exprNodes(list []ast.Expr) []ast.Node {
+	nodes := make([]ast.Node, len(list))
+	for i := range list {
+		nodes[i] = list[i]
+	}
+	return nodes
+}
+
+func stmtNodes(list []ast.Stmt) []ast.Node {
+	nodes := make([]ast.Node, len(list))
+	for i := range list {
+		nodes[i] = list[i]
+	}
+	return nodes
+}
+
+func declNodes(list []ast.Decl) []ast.Node {
+	nodes := make([]ast.Node, len(list))
+	for i := range list {
+		nodes[i] = list[i]
+	}
+	return nodes
+}
+
+func specNodes(list []ast.Spec) []ast.Node {
+	nodes := make([]ast.Node, len(list))
+	for i := range list {
+		nodes[i] = list[i]
+	}
+	return nodes
+}
+
+func identNodes(list []*ast.Ident) []ast.Node {
+	nodes := make([]ast.Node, len(list))
+	for i := range list {
+		nodes[i] = list[i]
+	}
+	return nodes
+}
+
+func fieldNodes(list []*ast.Field) []ast.Node {
+	nodes := make([]ast.Node, len(list))
+	for i := range list {
+		nodes[i] = list[i]
+	}
+	return nodes
+}
+
+func commentGroupNodes(list []*ast.CommentGroup) []ast.Node {
+	nodes := make([]ast.Node, len(list))
+	for i := range list {
+		nodes[i] = list[i]
+	}
+	return nodes
+}
+
+func commentNodes(list []*ast.Comment) []ast.Node {
+	nodes := make([]ast.Node, len(list))
+	for i := range list {
+		nodes[i] = list[i]
+	}
+	return nodes
+}
@@ -0,0 +1,147 @@
+package astequal
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		x      string
+		y      string
+		path   string
+		reason Reason
+	}{
+		{`x`, `y`, `Ident`, NameMismatch},
+		{`1`, `2`, `BasicLit`, ValueMismatch},
+		{`1`, `1.0`, `BasicLit`, ValueMismatch},
+		{`x`, `1`, `Ident`, KindMismatch},
+		{`x + y`, `x - y`, `BinaryExpr`, OperatorMismatch},
+		{`x + y`, `x + z`, `BinaryExpr.Y`, NameMismatch},
+		{`f(a, b)`, `f(a)`, `CallExpr.Args`, LengthMismatch},
+		{`f(a, b)`, `f(a, c)`, `CallExpr.Args[1]`, NameMismatch},
+		{`f(a, b)`, `f(a, b...)`, `CallExpr`, OtherMismatch},
+		{`a[x:y]`, `a[x:]`, `SliceExpr.High`, NilMismatch},
+		{`chan int`, `<-chan int`, `ChanType`, OtherMismatch},
+		{`func() { f(a.b) }`, `func() { f(a.c) }`, `FuncLit.Body.List[0].X.Args[0].Sel`, NameMismatch},
+		{`struct{a int}`, `struct{a string}`, `StructType.Fields.List[0].Type`, NameMismatch},
+		{`struct{a int}`, `struct{b int}`, `StructType.Fields.List[0].Names[0]`, NameMismatch},
+	}
+
+	for _, test := range tests {
+		x := strparse.Expr(test.x)
+		y := strparse.Expr(test.y)
+		m := Explain(x, y)
+		if m == nil {
+			t.Errorf("Explain(%s, %s): unexpected nil", test.x, test.y)
+			continue
+		}
+		if m.Path != test.path || m.Reason != test.reason {
+			t.Errorf("Explain(%s, %s):\nhave: %s\nwant: %s: %s",
+				test.x, test.y, m, test.path, test.reason)
+		}
+		if m.X == nil && m.Y == nil {
+			t.Errorf("Explain(%s, %s): no nodes reported", test.x, test.y)
+		}
+		if Explain(x, x) != nil {
+			t.Errorf("Explain(%s, %s): mismatch reported for equal nodes", test.x, test.x)
+		}
+	}
+}
+
+func TestExplainDecl(t *testing.T) {
+	x := strparse.Decl(`func f(a int) {
+		a++
+		g(a)
+		if a > 0 {
+			return h(a, 1)
+		}
+	}`)
+	y := strparse.Decl(`func f(a int) {
+		a++
+		g(a)
+		if a > 0 {
+			return h(a, 2)
+		}
+	}`)
+
+	m := Explain(x, y)
+	want := `FuncDecl.Body.List[2].Body.List[0].Results[0].Args[1]`
+	if m == nil || m.Path != want || m.Reason != ValueMismatch {
+		t.Fatalf("have: %v\nwant: %s: %s", m, want, ValueMismatch)
+	}
+	if m.X.(*ast.BasicLit).Value != "1" || m.Y.(*ast.BasicLit).Value != "2" {
+		t.Errorf("unexpected nodes: %v %v", m.X, m.Y)
+	}
+
+	m = Explain(strparse.Decl(`var x int`), strparse.Decl(`const x int`))
+	if m == nil || m.Path != "GenDecl" || m.Reason != OperatorMismatch {
+		t.Errorf("have: %v\nwant: GenDecl: %s", m, OperatorMismatch)
+	}
+
+	m = Explain(strparse.Stmt(`$`), strparse.Stmt(`$`))
+	if m == nil || m.Reason != BadNode {
+		t.Errorf("have: %v\nwant: BadStmt: %s", m, BadNode)
+	}
+}
+
+func TestExplainNil(t *testing.T) {
+	if !Node(nil, nil) {
+		t.Errorf("Node(nil, nil): have false, want true")
+	}
+	if m := Explain(nil, nil); m != nil {
+		t.Errorf("Explain(nil, nil): have %v, want nil", m)
+	}
+
+	m := Explain(nil, strparse.Expr(`x`))
+	if m == nil || m.Path != "Ident" || m.Reason != NilMismatch {
+		t.Errorf("have: %v\nwant: Ident: %s", m, NilMismatch)
+	}
+}
+
+func TestExplainComparer(t *testing.T) {
+	x := parseFile(t, "package foo\ntype T struct {\n// A.\nx int `json:\"x\"`\n}")
+	y := parseFile(t, "package foo\ntype T struct {\n// B.\nx int `json:\"y\"`\n}")
+
+	if m := Explain(x, y); m != nil {
+		t.Errorf("default: unexpected mismatch %v", m)
+	}
+
+	m := New(CompareComments()).Explain(x, y)
+	want := `File.Decls[0].Specs[0].Type.Fields.List[0].Doc.List[0]`
+	if m == nil || m.Path != want || m.Reason != ValueMismatch {
+		t.Errorf("comments:\nhave: %v\nwant: %s: %s", m, want, ValueMismatch)
+	}
+
+	m = New(CompareComments(), CompareTags(SemanticTags)).Explain(x, y)
+	want = `File.Decls[0].Specs[0].Type.Fields.List[0].Tag`
+	if m == nil || m.Path != want || m.Reason != ValueMismatch {
+		t.Errorf("tags:\nhave: %v\nwant: %s: %s", m, want, ValueMismatch)
+	}
+}
+
+func TestExplainEqualFunc(t *testing.T) {
+	// Report all additions unequal, while their operands are equal.
+	cmp := New(WithEqualFunc(func(x, y ast.Node) (bool, bool) {
+		if e, ok := x.(*ast.BinaryExpr); ok && e.Op.String() == "+" {
+			return false, true
+		}
+		return false, false
+	}))
+
+	x := strparse.Expr(`f(a + b)`)
+	y := strparse.Expr(`f(a + b)`)
+	want := `CallExpr.Args[0]: ` + OtherMismatch.String()
+
+	if m := cmp.Explain(x, y); m == nil || m.String() != want {
+		t.Errorf("Explain:\nhave: %v\nwant: %s", m, want)
+	}
+	if ms := cmp.ExplainAll(x, y); len(ms) != 1 || ms[0].String() != want {
+		t.Errorf("ExplainAll:\nhave: %v\nwant: [%s]", ms, want)
+	}
+	if r := cmp.Report(x, y); r.Equal || len(r.Mismatches) != 1 {
+		t.Errorf("Report: have %+v, want a single mismatch", r)
+	}
+}
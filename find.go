@@ -0,0 +1,82 @@
+package astequal
+
+import (
+	"go/ast"
+
+	"github.com/go-toolsmith/astequal/internal/walk"
+)
+
+// FindAll returns all root subtrees (including root itself)
+// that are equal to needle, as reported by Node, in the source order.
+//
+// Candidates are filtered by the node kind and size
+// before the full comparison, so the search takes
+// a single pass over root, plus a few comparisons.
+func FindAll(root, needle ast.Node) []ast.Node {
+	var found []ast.Node
+	find(root, needle, func(n ast.Node) bool {
+		found = append(found, n)
+		return true
+	})
+	return found
+}
+
+// Contains reports whether root contains a subtree
+// (or is itself a tree) equal to needle.
+//
+// See FindAll.
+func Contains(root, needle ast.Node) bool {
+	found := false
+	find(root, needle, func(ast.Node) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+// find calls visit for every root subtree equal to needle,
+// until visit returns false.
+//
+// Equal subtrees have the same size, so they can't be nested.
+// Therefore the post-order traversal yields them in the source order.
+func find(root, needle ast.Node, visit func(ast.Node) bool) {
+	if isNilNode(root) || isNilNode(needle) {
+		return
+	}
+	tag := nodeTagOf(needle)
+	size := nodeSize(needle)
+	var c comparer
+	done := false
+	walkSizes(root, func(n ast.Node, nsize int) bool {
+		if done {
+			return false
+		}
+		if nsize == size && nodeTagOf(n) == tag && c.astNodeEq(needle, n) {
+			done = !visit(n)
+		}
+		return !done
+	})
+}
+
+// nodeSize returns the number of nodes in the n tree
+// that are relevant for the comparison.
+func nodeSize(n ast.Node) int {
+	size := 0
+	walkSizes(n, func(n ast.Node, nsize int) bool {
+		size = nsize
+		return true
+	})
+	return size
+}
+
+// walkSizes calls visit in the post-order for every n subtree
+// with its size, until visit returns false.
+//
+// Comments and field tags are not taken into account,
+// as they are ignored by the default comparison.
+func walkSizes(n ast.Node, visit func(n ast.Node, size int) bool) {
+	walk.Fold(n,
+		func(ast.Node) int { return 1 },
+		func(parent *int, child int) { *parent += child },
+		visit)
+}
@@ -0,0 +1,92 @@
+package astequal
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestFindAll(t *testing.T) {
+	const src = `package foo
+
+type T struct {
+	x int ` + "`json:\"x\"`" + `
+}
+
+func f(a, b int) int {
+	if a+b > 0 {
+		return g(a + b)
+	}
+	// Comment.
+	return (a + b) * (a + b)
+}
+
+func g(x int) int {
+	return x + (a + b)
+}
+`
+	f, err := parser.ParseFile(token.NewFileSet(), "foo.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	tests := []struct {
+		needle ast.Node
+		count  int
+	}{
+		{strparse.Expr(`a + b`), 5},
+		{strparse.Expr(`(a + b)`), 3},
+		{strparse.Expr(`a`), 6},
+		{strparse.Expr(`g(a + b)`), 1},
+		{strparse.Expr(`g(a - b)`), 0},
+		{strparse.Expr(`struct{ x int }`), 1},
+		{strparse.Stmt(`return x + (a + b)`), 1},
+		{strparse.Stmt(`{ return g(a + b) }`), 1},
+		{strparse.Expr(`b + a`), 0},
+	}
+
+	for _, test := range tests {
+		found := FindAll(f, test.needle)
+		if len(found) != test.count {
+			t.Errorf("FindAll(%T): have %d nodes, want %d",
+				test.needle, len(found), test.count)
+		}
+		for i, n := range found {
+			if !Node(n, test.needle) {
+				t.Errorf("FindAll(%T): found node is not equal to needle", test.needle)
+			}
+			if i > 0 && found[i-1].Pos() >= n.Pos() {
+				t.Errorf("FindAll(%T): found nodes are not in the source order", test.needle)
+			}
+		}
+		if have := Contains(f, test.needle); have != (test.count != 0) {
+			t.Errorf("Contains(%T): have %v, want %v",
+				test.needle, have, test.count != 0)
+		}
+	}
+
+	if !Contains(f, f) {
+		t.Errorf("Contains: root doesn't contain itself")
+	}
+	if FindAll(f, nil) != nil || FindAll(nil, f) != nil || Contains(f, nil) {
+		t.Errorf("nil arguments must not match anything")
+	}
+}
+
+func BenchmarkFindAll(b *testing.B) {
+	f, err := parser.ParseFile(token.NewFileSet(), "astequal.go", nil, 0)
+	if err != nil {
+		b.Fatalf("parse: %v", err)
+	}
+	needle := strparse.Expr(`x == y`)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(FindAll(f, needle)) == 0 {
+			b.Fatal("no nodes found")
+		}
+	}
+}
@@ -2,7 +2,4 @@ module github.com/go-toolsmith/astequal
 
 go 1.18
 
-require (
-	github.com/go-toolsmith/strparse v1.1.0
-	github.com/google/go-cmp v0.6.0
-)
+require github.com/go-toolsmith/strparse v1.1.0
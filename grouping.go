@@ -0,0 +1,226 @@
+package astequal
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// ungroupedDeclSliceEq compares declaration lists as if every
+// declaration specified a single name.
+func (c *comparer) ungroupedDeclSliceEq(xs, ys []ast.Decl) bool {
+	xs, xiotas := ungroupDecls(xs)
+	ys, yiotas := ungroupDecls(ys)
+	if len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		if !iotaEq(xs[i], ys[i], xiotas[i], yiotas[i]) || !c.astDeclEq(xs[i], ys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ungroupedStmtSliceEq is like ungroupedDeclSliceEq,
+// but for the declaration statements.
+func (c *comparer) ungroupedStmtSliceEq(xs, ys []ast.Stmt) bool {
+	xs, xiotas := ungroupStmts(xs)
+	ys, yiotas := ungroupStmts(ys)
+	if len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		xdecl, ydecl := declOf(xs[i]), declOf(ys[i])
+		if !iotaEq(xdecl, ydecl, xiotas[i], yiotas[i]) || !c.astStmtEq(xs[i], ys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// stmtsEdge returns the statement lists edge to explain,
+// see astStmtSliceEq.
+func (c *comparer) stmtsEdge(name string, xs, ys []ast.Stmt) explainEdge {
+	if !c.ungroupDecls {
+		return listEdge(name, stmtNodes(xs), stmtNodes(ys))
+	}
+	xs, xiotas := ungroupStmts(xs)
+	ys, yiotas := ungroupStmts(ys)
+	e := listEdge(name, stmtNodes(xs), stmtNodes(ys))
+	e.xiotas, e.yiotas = xiotas, yiotas
+	return e
+}
+
+// declsEdge is like stmtsEdge, but for the declaration lists.
+func (c *comparer) declsEdge(name string, xs, ys []ast.Decl) explainEdge {
+	if !c.ungroupDecls {
+		return listEdge(name, declNodes(xs), declNodes(ys))
+	}
+	xs, xiotas := ungroupDecls(xs)
+	ys, yiotas := ungroupDecls(ys)
+	e := listEdge(name, declNodes(xs), declNodes(ys))
+	e.xiotas, e.yiotas = xiotas, yiotas
+	return e
+}
+
+func ungroupDecls(decls []ast.Decl) ([]ast.Decl, []int) {
+	var list []ast.Decl
+	var iotas []int
+	for _, decl := range decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen == nil {
+			list = append(list, decl)
+			iotas = append(iotas, -1)
+			continue
+		}
+		split, splitIotas := splitGenDecl(gen)
+		for _, d := range split {
+			list = append(list, d)
+		}
+		iotas = append(iotas, splitIotas...)
+	}
+	return list, iotas
+}
+
+func ungroupStmts(stmts []ast.Stmt) ([]ast.Stmt, []int) {
+	var list []ast.Stmt
+	var iotas []int
+	for _, stmt := range stmts {
+		gen, ok := declOf(stmt).(*ast.GenDecl)
+		if !ok || gen == nil {
+			list = append(list, stmt)
+			iotas = append(iotas, -1)
+			continue
+		}
+		split, splitIotas := splitGenDecl(gen)
+		for _, d := range split {
+			list = append(list, &ast.DeclStmt{Decl: d})
+		}
+		iotas = append(iotas, splitIotas...)
+	}
+	return list, iotas
+}
+
+// nodeDecl returns n itself if it's a declaration,
+// or its declaration if it's a declaration statement.
+func nodeDecl(n ast.Node) ast.Decl {
+	switch n := n.(type) {
+	case ast.Decl:
+		return n
+	case ast.Stmt:
+		return declOf(n)
+	default:
+		return nil
+	}
+}
+
+// declOf returns the stmt declaration, if it's a declaration statement.
+func declOf(stmt ast.Stmt) ast.Decl {
+	if stmt, ok := stmt.(*ast.DeclStmt); ok && stmt != nil {
+		return stmt.Decl
+	}
+	return nil
+}
+
+// splitGenDecl splits the decl into declarations with a single spec
+// that declares a single name, if possible.
+// Specs that can't be split, like var a, b = f(), are kept as is.
+//
+// The implicit repetition of the const specs is made explicit,
+// and the iota value of every const declaration is returned.
+// It's -1 for other declarations.
+func splitGenDecl(decl *ast.GenDecl) ([]*ast.GenDecl, []int) {
+	var list []*ast.GenDecl
+	var iotas []int
+	add := func(spec ast.Spec, doc *ast.CommentGroup, iota int) {
+		if !decl.Lparen.IsValid() {
+			doc = decl.Doc
+		}
+		list = append(list, &ast.GenDecl{
+			Doc:    doc,
+			TokPos: decl.TokPos,
+			Tok:    decl.Tok,
+			Specs:  []ast.Spec{spec},
+		})
+		iotas = append(iotas, iota)
+	}
+
+	var typ ast.Expr
+	var values []ast.Expr
+	for i, spec := range decl.Specs {
+		switch spec := spec.(type) {
+		case *ast.ValueSpec:
+			if spec == nil {
+				break
+			}
+			iota := -1
+			if decl.Tok == token.CONST {
+				iota = i
+				if spec.Type != nil || len(spec.Values) != 0 {
+					typ, values = spec.Type, spec.Values
+				}
+			} else {
+				typ, values = spec.Type, spec.Values
+			}
+			if len(values) != 0 && len(values) != len(spec.Names) {
+				add(spec, spec.Doc, iota)
+				continue
+			}
+			for j, name := range spec.Names {
+				single := &ast.ValueSpec{
+					Names:   []*ast.Ident{name},
+					Type:    typ,
+					Comment: spec.Comment,
+				}
+				if len(values) != 0 {
+					single.Values = []ast.Expr{values[j]}
+				}
+				add(single, spec.Doc, iota)
+			}
+			continue
+		case *ast.TypeSpec:
+			if spec == nil {
+				break
+			}
+			single := *spec
+			single.Doc = nil
+			add(&single, spec.Doc, -1)
+			continue
+		case *ast.ImportSpec:
+			if spec == nil {
+				break
+			}
+			single := *spec
+			single.Doc = nil
+			add(&single, spec.Doc, -1)
+			continue
+		}
+		add(spec, nil, -1)
+	}
+	if len(decl.Specs) == 0 {
+		list = append(list, decl)
+		iotas = append(iotas, -1)
+	}
+	return list, iotas
+}
+
+// iotaEq reports whether x and y declarations have the same iota value,
+// if any of them depends on it.
+func iotaEq(x, y ast.Decl, xiota, yiota int) bool {
+	return xiota == yiota || (!usesIota(x) && !usesIota(y))
+}
+
+// usesIota reports whether n refers to the iota identifier.
+func usesIota(n ast.Node) bool {
+	if n == nil {
+		return false
+	}
+	found := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "iota" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
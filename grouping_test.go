@@ -0,0 +1,124 @@
+package astequal
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestIgnoreDeclGrouping(t *testing.T) {
+	tests := []struct {
+		x     string
+		y     string
+		equal bool
+	}{
+		{"var (\na int\nb string\n)", "var a int\nvar b string", true},
+		{"var x, y int", "var x int\nvar y int", true},
+		{"var x, y = 1, 2", "var (\nx = 1\ny = 2\n)", true},
+		{"var a, b = f()", "var a, b = f()", true},
+		{"var a, b = f()", "var a = f()\nvar b = f()", false},
+		{"var (\na int\nb string\n)", "var b string\nvar a int", false},
+		{"var a int", "const a int", false},
+		{"const (\na = 1\nb = 2\n)", "const a = 1\nconst b = 2", true},
+		{"const (\na = iota\nb\n)", "const (\na = iota\nb = iota\n)", true},
+		{"const (\na = iota\nb\n)", "const a = iota\nconst b = iota", false},
+		{"const (\na = iota\nb\n)", "const (\na, b = iota, iota\n)", false},
+		{"const (\n_ = iota\na\n)", "const (\n_, a = iota, iota + 1\n)", false},
+		{"const (\na int = 1\nb\n)", "const a, b int = 1, 1", true},
+		{"const (\na = 1\nb\n)", "const a, b = 1, 2", false},
+		{"type (\nA int\nB string\n)", "type A int\ntype B string", true},
+		{"type (\nA int\nB[T any] []T\n)", "type A int\ntype B[T any] []T", true},
+		{"import (\n\"a\"\n\"b\"\n)", "import \"a\"\nimport \"b\"", true},
+		{"import (\n\"a\"\nb \"b\"\n)", "import \"a\"\nimport \"b\"", false},
+		{"var (\na int\n)\nfunc f() {}", "var a int\nfunc f() {}", true},
+		{"func f() {}\nvar (\na int\n)", "var a int\nfunc f() {}", false},
+	}
+
+	cmp := New(IgnoreDeclGrouping())
+	for _, test := range tests {
+		x := parseFile(t, "package foo\n"+test.x)
+		y := parseFile(t, "package foo\n"+test.y)
+		if have := cmp.File(x, y); have != test.equal {
+			t.Errorf("File:\nx: %q\ny: %q\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.equal)
+		}
+	}
+}
+
+func TestIgnoreDeclGroupingStmt(t *testing.T) {
+	tests := []struct {
+		x     string
+		y     string
+		equal bool
+	}{
+		{`{ var (a int; b string); f(a, b) }`, `{ var a int; var b string; f(a, b) }`, true},
+		{`{ var x, y int }`, `{ var x int; var y int }`, true},
+		{`{ const (a = iota; b) }`, `{ const a = iota; const b = iota }`, false},
+		{`{ var (a int; b string) }`, `{ var a int; f(); var b string }`, false},
+	}
+
+	for _, cmp := range []*Comparer{
+		New(IgnoreDeclGrouping()),
+		New(IgnoreDeclGrouping(), AlphaEquivalence()),
+	} {
+		for _, test := range tests {
+			x := strparse.Stmt(test.x)
+			y := strparse.Stmt(test.y)
+			if have := cmp.Stmt(x, y); have != test.equal {
+				t.Errorf("Stmt:\nx: %s\ny: %s\nhave: %v\nwant: %v",
+					test.x, test.y, have, test.equal)
+			}
+		}
+	}
+
+	cmp := New(IgnoreDeclGrouping(), AlphaEquivalence())
+	x := strparse.Stmt(`{ var (a int; b string); f(a, b) }`)
+	y := strparse.Stmt(`{ var c int; var d string; f(c, d) }`)
+	if !cmp.Stmt(x, y) {
+		t.Errorf("renamed ungrouped declarations reported as not equal")
+	}
+}
+
+func TestIgnoreDeclGroupingComments(t *testing.T) {
+	x := parseFile(t, "package foo\nvar (\n// A.\na int\n// B.\nb int\n)")
+	y := parseFile(t, "package foo\n// A.\nvar a int\n\n// B.\nvar b int")
+	if !New(IgnoreDeclGrouping(), CompareComments()).File(x, y) {
+		t.Errorf("docs of regrouped declarations reported as not equal")
+	}
+	y = parseFile(t, "package foo\n// A.\nvar a int\n\n// Changed.\nvar b int")
+	if New(IgnoreDeclGrouping(), CompareComments()).File(x, y) {
+		t.Errorf("changed docs of regrouped declarations reported as equal")
+	}
+}
+
+func TestIgnoreDeclGroupingExplain(t *testing.T) {
+	tests := []struct {
+		x    ast.Node
+		y    ast.Node
+		want string
+	}{
+		{
+			strparse.Stmt(`{ var (a int; b int); _ = a }`),
+			strparse.Stmt(`{ var a int; var b string; _ = a }`),
+			`BlockStmt.List[1].Decl.Specs[0].Type: name mismatch`,
+		},
+		{
+			strparse.Stmt(`{ const (a = iota; b) }`),
+			strparse.Stmt(`{ const a = iota; const b = iota }`),
+			`BlockStmt.List[1]: mismatch`,
+		},
+		{
+			parseFile(t, "package foo\nvar (\na int\nb int\n)\nfunc f() {}"),
+			parseFile(t, "package foo\nvar a int\nvar b int\nfunc g() {}"),
+			`File.Decls[2].Name: name mismatch`,
+		},
+	}
+
+	cmp := New(IgnoreDeclGrouping())
+	for _, test := range tests {
+		if m := cmp.Explain(test.x, test.y); m == nil || m.String() != test.want {
+			t.Errorf("Explain:\nhave: %v\nwant: %s", m, test.want)
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package astequal
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// Hash returns a structural hash of the AST node.
+//
+// Hash is consistent with the Node function:
+// if Node(x, y) is true, then Hash(x) == Hash(y).
+// Like the comparison functions, it ignores positions,
+// comments and field tags.
+//
+// The result only depends on the node structure,
+// so it's stable across processes, architectures and Go releases.
+// It may change between astequal versions, though.
+func Hash(n ast.Node) uint64 {
+	h := hasher{sum: fnvOffset64}
+	h.node(n)
+	return h.sum
+}
+
+// FNV-1a parameters.
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// nodeTag identifies a node type for hashing and ordering.
+type nodeTag uint8
+
+const (
+	tagNil nodeTag = iota
+
+	tagBadExpr
+	tagIdent
+	tagBasicLit
+	tagFuncLit
+	tagCompositeLit
+	tagParenExpr
+	tagSelectorExpr
+	tagIndexExpr
+	tagIndexListExpr
+	tagSliceExpr
+	tagTypeAssertExpr
+	tagCallExpr
+	tagStarExpr
+	tagUnaryExpr
+	tagBinaryExpr
+	tagKeyValueExpr
+	tagArrayType
+	tagStructType
+	tagFuncType
+	tagInterfaceType
+	tagMapType
+	tagChanType
+	tagEllipsis
+
+	tagBadStmt
+	tagExprStmt
+	tagSendStmt
+	tagIncDecStmt
+	tagAssignStmt
+	tagGoStmt
+	tagDeferStmt
+	tagReturnStmt
+	tagBranchStmt
+	tagBlockStmt
+	tagIfStmt
+	tagCaseClause
+	tagSwitchStmt
+	tagTypeSwitchStmt
+	tagCommClause
+	tagSelectStmt
+	tagForStmt
+	tagRangeStmt
+	tagDeclStmt
+	tagLabeledStmt
+	tagEmptyStmt
+
+	tagBadDecl
+	tagGenDecl
+	tagFuncDecl
+
+	tagImportSpec
+	tagTypeSpec
+	tagValueSpec
+
+	tagField
+	tagFieldList
+	tagFile
+	tagCommentGroup
+	tagComment
+
+	// tagUnknown is used for node types that are not supported
+	// by the comparison functions.
+	tagUnknown
+)
+
+// hasher computes FNV-1a hash of the AST node structure.
+type hasher struct {
+	sum uint64
+}
+
+func (h *hasher) byte(b byte) {
+	h.sum ^= uint64(b)
+	h.sum *= fnvPrime64
+}
+
+func (h *hasher) tag(t nodeTag) {
+	h.byte(byte(t))
+}
+
+func (h *hasher) int(v int) {
+	u := uint64(v)
+	for i := 0; i < 8; i++ {
+		h.byte(byte(u >> (8 * i)))
+	}
+}
+
+func (h *hasher) bool(v bool) {
+	if v {
+		h.byte(1)
+	} else {
+		h.byte(0)
+	}
+}
+
+func (h *hasher) string(s string) {
+	h.int(len(s))
+	for i := 0; i < len(s); i++ {
+		h.byte(s[i])
+	}
+}
+
+// token hashes the token spelling, as token.Token values
+// may change between Go releases.
+func (h *hasher) token(t token.Token) {
+	h.string(t.String())
+}
+
+// chanDir hashes a fixed encoding of the channel direction,
+// as ast.ChanDir values may change between Go releases.
+func (h *hasher) chanDir(dir ast.ChanDir) {
+	switch dir {
+	case ast.SEND:
+		h.byte(1)
+	case ast.RECV:
+		h.byte(2)
+	case ast.SEND | ast.RECV:
+		h.byte(3)
+	default:
+		h.byte(0)
+	}
+}
+
+func (h *hasher) node(n ast.Node) {
+	if isNilNode(n) {
+		h.tag(tagNil)
+		return
+	}
+
+	switch n := n.(type) {
+	case *ast.BadExpr:
+		h.tag(tagBadExpr)
+	case *ast.Ident:
+		h.tag(tagIdent)
+		h.string(n.Name)
+	case *ast.BasicLit:
+		h.tag(tagBasicLit)
+		h.token(n.Kind)
+		h.string(n.Value)
+	case *ast.FuncLit:
+		h.tag(tagFuncLit)
+		h.node(n.Type)
+		h.node(n.Body)
+	case *ast.CompositeLit:
+		h.tag(tagCompositeLit)
+		h.node(n.Type)
+		h.exprList(n.Elts)
+	case *ast.ParenExpr:
+		h.tag(tagParenExpr)
+		h.node(n.X)
+	case *ast.SelectorExpr:
+		h.tag(tagSelectorExpr)
+		h.node(n.X)
+		h.node(n.Sel)
+	case *ast.IndexExpr:
+		h.tag(tagIndexExpr)
+		h.node(n.X)
+		h.node(n.Index)
+	case *ast.IndexListExpr:
+		h.tag(tagIndexListExpr)
+		h.node(n.X)
+		h.exprList(n.Indices)
+	case *ast.SliceExpr:
+		h.tag(tagSliceExpr)
+		h.node(n.X)
+		h.node(n.Low)
+		h.node(n.High)
+		h.node(n.Max)
+	case *ast.TypeAssertExpr:
+		h.tag(tagTypeAssertExpr)
+		h.node(n.X)
+		h.node(n.Type)
+	case *ast.CallExpr:
+		h.tag(tagCallExpr)
+		h.node(n.Fun)
+		h.exprList(n.Args)
+		h.bool(n.Ellipsis != 0)
+	case *ast.StarExpr:
+		h.tag(tagStarExpr)
+		h.node(n.X)
+	case *ast.UnaryExpr:
+		h.tag(tagUnaryExpr)
+		h.token(n.Op)
+		h.node(n.X)
+	case *ast.BinaryExpr:
+		h.tag(tagBinaryExpr)
+		h.token(n.Op)
+		h.node(n.X)
+		h.node(n.Y)
+	case *ast.KeyValueExpr:
+		h.tag(tagKeyValueExpr)
+		h.node(n.Key)
+		h.node(n.Value)
+	case *ast.ArrayType:
+		h.tag(tagArrayType)
+		h.node(n.Len)
+		h.node(n.Elt)
+	case *ast.StructType:
+		h.tag(tagStructType)
+		h.node(n.Fields)
+	case *ast.FuncType:
+		h.tag(tagFuncType)
+		h.node(n.Params)
+		h.node(n.Results)
+		h.node(n.TypeParams)
+	case *ast.InterfaceType:
+		h.tag(tagInterfaceType)
+		h.node(n.Methods)
+	case *ast.MapType:
+		h.tag(tagMapType)
+		h.node(n.Key)
+		h.node(n.Value)
+	case *ast.ChanType:
+		h.tag(tagChanType)
+		h.chanDir(n.Dir)
+		h.node(n.Value)
+	case *ast.Ellipsis:
+		h.tag(tagEllipsis)
+		h.node(n.Elt)
+
+	case *ast.BadStmt:
+		h.tag(tagBadStmt)
+	case *ast.ExprStmt:
+		h.tag(tagExprStmt)
+		h.node(n.X)
+	case *ast.SendStmt:
+		h.tag(tagSendStmt)
+		h.node(n.Chan)
+		h.node(n.Value)
+	case *ast.IncDecStmt:
+		h.tag(tagIncDecStmt)
+		h.token(n.Tok)
+		h.node(n.X)
+	case *ast.AssignStmt:
+		h.tag(tagAssignStmt)
+		h.token(n.Tok)
+		h.exprList(n.Lhs)
+		h.exprList(n.Rhs)
+	case *ast.GoStmt:
+		h.tag(tagGoStmt)
+		h.node(n.Call)
+	case *ast.DeferStmt:
+		h.tag(tagDeferStmt)
+		h.node(n.Call)
+	case *ast.ReturnStmt:
+		h.tag(tagReturnStmt)
+		h.exprList(n.Results)
+	case *ast.BranchStmt:
+		h.tag(tagBranchStmt)
+		h.token(n.Tok)
+		h.node(n.Label)
+	case *ast.BlockStmt:
+		h.tag(tagBlockStmt)
+		h.stmtList(n.List)
+	case *ast.IfStmt:
+		h.tag(tagIfStmt)
+		h.node(n.Init)
+		h.node(n.Cond)
+		h.node(n.Body)
+		h.node(n.Else)
+	case *ast.CaseClause:
+		h.tag(tagCaseClause)
+		h.exprList(n.List)
+		h.stmtList(n.Body)
+	case *ast.SwitchStmt:
+		h.tag(tagSwitchStmt)
+		h.node(n.Init)
+		h.node(n.Tag)
+		h.node(n.Body)
+	case *ast.TypeSwitchStmt:
+		h.tag(tagTypeSwitchStmt)
+		h.node(n.Init)
+		h.node(n.Assign)
+		h.node(n.Body)
+	case *ast.CommClause:
+		h.tag(tagCommClause)
+		h.node(n.Comm)
+		h.stmtList(n.Body)
+	case *ast.SelectStmt:
+		h.tag(tagSelectStmt)
+		h.node(n.Body)
+	case *ast.ForStmt:
+		h.tag(tagForStmt)
+		h.node(n.Init)
+		h.node(n.Cond)
+		h.node(n.Post)
+		h.node(n.Body)
+	case *ast.RangeStmt:
+		h.tag(tagRangeStmt)
+		h.token(n.Tok)
+		h.node(n.Key)
+		h.node(n.Value)
+		h.node(n.X)
+		h.node(n.Body)
+	case *ast.DeclStmt:
+		h.tag(tagDeclStmt)
+		h.node(n.Decl)
+	case *ast.LabeledStmt:
+		h.tag(tagLabeledStmt)
+		h.node(n.Label)
+		h.node(n.Stmt)
+	case *ast.EmptyStmt:
+		h.tag(tagEmptyStmt)
+		h.bool(n.Implicit)
+
+	case *ast.BadDecl:
+		h.tag(tagBadDecl)
+	case *ast.GenDecl:
+		h.tag(tagGenDecl)
+		h.token(n.Tok)
+		h.int(len(n.Specs))
+		for _, spec := range n.Specs {
+			h.node(spec)
+		}
+	case *ast.FuncDecl:
+		h.tag(tagFuncDecl)
+		h.node(n.Recv)
+		h.node(n.Name)
+		h.node(n.Type)
+		h.node(n.Body)
+
+	case *ast.ImportSpec:
+		h.tag(tagImportSpec)
+		h.node(n.Name)
+		h.node(n.Path)
+	case *ast.TypeSpec:
+		h.tag(tagTypeSpec)
+		h.node(n.Name)
+		h.node(n.Type)
+		h.node(n.TypeParams)
+	case *ast.ValueSpec:
+		h.tag(tagValueSpec)
+		h.identList(n.Names)
+		h.node(n.Type)
+		h.exprList(n.Values)
+
+	case *ast.Field:
+		h.tag(tagField)
+		h.identList(n.Names)
+		h.node(n.Type)
+	case *ast.FieldList:
+		h.tag(tagFieldList)
+		h.int(len(n.List))
+		for _, field := range n.List {
+			h.node(field)
+		}
+	case *ast.File:
+		h.tag(tagFile)
+		h.node(n.Name)
+		h.string(buildConstraint(n))
+		h.int(len(n.Decls))
+		for _, decl := range n.Decls {
+			h.node(decl)
+		}
+
+	case *ast.CommentGroup:
+		h.tag(tagCommentGroup)
+		h.int(len(n.List))
+		for _, c := range n.List {
+			h.node(c)
+		}
+	case *ast.Comment:
+		h.tag(tagComment)
+		h.string(n.Text)
+
+	default:
+		h.tag(tagUnknown)
+	}
+}
+
+func (h *hasher) exprList(list []ast.Expr) {
+	h.int(len(list))
+	for _, x := range list {
+		h.node(x)
+	}
+}
+
+func (h *hasher) stmtList(list []ast.Stmt) {
+	h.int(len(list))
+	for _, x := range list {
+		h.node(x)
+	}
+}
+
+func (h *hasher) identList(list []*ast.Ident) {
+	h.int(len(list))
+	for _, x := range list {
+		h.node(x)
+	}
+}
@@ -0,0 +1,102 @@
+package astequal
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestHashConsistency(t *testing.T) {
+	nodes := sampleNodes(t)
+
+	hashes := make(map[uint64]ast.Node)
+	for _, x := range nodes {
+		hx := Hash(x)
+		for _, y := range nodes {
+			if Node(x, y) && hx != Hash(y) {
+				t.Errorf("equal nodes have different hashes:\nx: %#v\ny: %#v", x, y)
+			}
+		}
+		if y, ok := hashes[hx]; ok && !Node(x, y) {
+			t.Errorf("hash collision:\nx: %#v\ny: %#v", x, y)
+		}
+		hashes[hx] = x
+	}
+}
+
+// sampleNodes returns a set of nodes where every node has an equal twin.
+func sampleNodes(t testing.TB) []ast.Node {
+	exprs := []string{
+		`x`, `y`, `1`, `1.0`, `"x"`, `'x'`,
+		`x + y`, `x - y`, `y + x`, `(x + y)`,
+		`f(x)`, `f(x...)`, `f(x, y)`, `f()(x)`,
+		`a.b`, `a.b.c`, `a[x]`, `a[x, y]`, `a[x:y]`, `a[x:y:z]`, `a[:]`,
+		`x.(int)`, `x.(type)`, `*x`, `&x`, `-x`, `<-x`,
+		`T{}`, `T{1}`, `T{a: 1}`, `[]int{1, 2}`, `map[a]b{}`,
+		`[2]int`, `[]int`, `[...]int`, `map[a]b`, `chan a`, `<-chan a`, `chan<- a`,
+		`struct{}`, `struct{a int}`, "struct{a int `json:\"a\"`}", `struct{a, b int}`,
+		`interface{}`, `interface{f()}`, `interface{g()}`,
+		`func()`, `func(x int)`, `func(int)`, `func() int`, `func(x ...int)`,
+		`func() {}`, `func() { f() }`, `func() { g() }`,
+	}
+	stmts := []string{
+		`x++`, `x--`, `x = y`, `x := y`, `x += y`, `ch <- x`,
+		`go f()`, `defer f()`, `return`, `return x`,
+		`break`, `continue`, `break L`, `goto L`, `fallthrough`,
+		`{}`, `{f()}`, `{f(); g()}`,
+		`if x {}`, `if x {} else {}`, `if y := x; y {}`,
+		`switch {}`, `switch x {case 1: f()}`, `switch x.(type) {case int: f()}`,
+		`select {}`, `select {case <-ch: f()}`,
+		`for {}`, `for x {}`, `for i := 0; i < n; i++ {}`,
+		`for range xs {}`, `for x := range xs {}`, `for x = range xs {}`,
+		`var x int`, `const x = 1`, `type T int`, `L: f()`, `;`,
+	}
+	decls := []string{
+		`func f() {}`, `func g() {}`, `func (T) f() {}`, `func f[T any]() {}`,
+		`import "a"`, `import x "a"`, `var (x int; y int)`, `var x, y int`,
+		`type T[X any] int`, `type T int`,
+	}
+
+	var nodes []ast.Node
+	for _, s := range exprs {
+		nodes = append(nodes, strparse.Expr(s), strparse.Expr(s))
+	}
+	for _, s := range stmts {
+		nodes = append(nodes, strparse.Stmt(s), strparse.Stmt(s))
+	}
+	for _, s := range decls {
+		nodes = append(nodes, strparse.Decl(s), strparse.Decl(s))
+	}
+	nodes = append(nodes,
+		parseFile(t, "package foo"), parseFile(t, "package foo"),
+		parseFile(t, "//go:build linux\n\npackage foo"), parseFile(t, "// +build linux\n\npackage foo"),
+		nil, nil)
+	return nodes
+}
+
+func TestHashStable(t *testing.T) {
+	// The hash must not depend on the process, the architecture
+	// or the token and channel direction values of the Go release.
+	tests := []struct {
+		src  string
+		want uint64
+	}{
+		{`x`, 0x7b2ec7592fc3074},
+		{`f(x+y, a.b.c()/(-d), xs...)`, 0xab1aedf4764ef1d2},
+		{`chan<- a`, 0x5214380b8e68ab8a},
+		{`<-chan a`, 0xbd6b7538c8b11c2b},
+	}
+	for _, test := range tests {
+		if have := Hash(strparse.Expr(test.src)); have != test.want {
+			t.Errorf("Hash(%s): have %#x, want %#x", test.src, have, test.want)
+		}
+	}
+}
+
+func BenchmarkHash(b *testing.B) {
+	x := strparse.Expr(`f(x+y, a.b.c()/(-d), xs...)+first(g1)`)
+	for i := 0; i < b.N; i++ {
+		Hash(x)
+	}
+}
@@ -0,0 +1,57 @@
+// Package walk implements the AST traversal shared by astequal packages.
+package walk
+
+import (
+	"go/ast"
+)
+
+// Fold folds every n subtree into a value, in the post-order.
+//
+// The leaf function returns a node value without its children,
+// add adds a child subtree value to its parent node value,
+// and visit is called for every complete subtree value,
+// until it returns false.
+//
+// Comments and field tags are skipped,
+// as they are ignored by the default comparison.
+func Fold[T any](n ast.Node, leaf func(ast.Node) T, add func(parent *T, child T), visit func(ast.Node, T) bool) {
+	var stack []frame[T]
+	var tags map[*ast.BasicLit]bool
+	stopped := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		if stopped {
+			return false
+		}
+		switch n := n.(type) {
+		case nil:
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) != 0 {
+				add(&stack[len(stack)-1].value, top.value)
+			}
+			stopped = !visit(top.node, top.value)
+			return false
+		case *ast.Comment, *ast.CommentGroup:
+			return false
+		case *ast.BasicLit:
+			if tags[n] {
+				return false
+			}
+		case *ast.Field:
+			if n.Tag != nil {
+				if tags == nil {
+					tags = make(map[*ast.BasicLit]bool)
+				}
+				tags[n.Tag] = true
+			}
+		}
+		stack = append(stack, frame[T]{node: n, value: leaf(n)})
+		return true
+	})
+}
+
+// frame is a subtree that is being folded.
+type frame[T any] struct {
+	node  ast.Node
+	value T
+}
@@ -0,0 +1,26 @@
+package astequal
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+)
+
+// literalValueEq reports whether x and y literals
+// are of the same kind and denote the same constant value.
+//
+// Literals that can't be evaluated are compared by their text.
+func literalValueEq(x, y *ast.BasicLit) bool {
+	if x.Kind != y.Kind {
+		return false
+	}
+	if x.Value == y.Value {
+		return true
+	}
+	xv := constant.MakeFromLiteral(x.Value, x.Kind, 0)
+	yv := constant.MakeFromLiteral(y.Value, y.Kind, 0)
+	if xv.Kind() == constant.Unknown || yv.Kind() == constant.Unknown {
+		return false
+	}
+	return constant.Compare(xv, token.EQL, yv)
+}
@@ -0,0 +1,57 @@
+package astequal
+
+import (
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestCompareLiteralValues(t *testing.T) {
+	tests := []struct {
+		x     string
+		y     string
+		equal bool
+	}{
+		{`16`, `16`, true},
+		{`0x10`, `16`, true},
+		{`0o20`, `020`, true},
+		{`0b10000`, `16`, true},
+		{`1_000`, `1000`, true},
+		{`1000`, `1001`, false},
+		{`1.5`, `15e-1`, true},
+		{`1.0`, `1.`, true},
+		{`0x1p4`, `16.0`, true},
+		{`1`, `1.0`, false},
+		{`2i`, `2.0i`, true},
+		{`2i`, `3i`, false},
+		{`'\x41'`, `'A'`, true},
+		{`'A'`, `'A'`, true},
+		{`'A'`, `65`, false},
+		{"`abc`", `"abc"`, true},
+		{"`a\\b`", `"a\\b"`, true},
+		{"`a\\b`", `"a\b"`, false},
+		{`"ä"`, `"ä"`, true},
+		{`"a"`, `"b"`, false},
+		{`f(0x10, "a")`, "f(16, `a`)", true},
+	}
+
+	cmp := New(CompareLiteralValues())
+	for _, test := range tests {
+		x := strparse.Expr(test.x)
+		y := strparse.Expr(test.y)
+		if have := cmp.Expr(x, y); have != test.equal {
+			t.Errorf("Expr:\nx: %s\ny: %s\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.equal)
+		}
+		if have := Expr(x, y); have != (test.x == test.y) {
+			t.Errorf("default Expr:\nx: %s\ny: %s\nhave: %v", test.x, test.y, have)
+		}
+	}
+
+	// Tags are not affected.
+	x := strparse.Expr("struct{a int `json:\"a\"`}")
+	y := strparse.Expr(`struct{a int "json:\"a\""}`)
+	if New(CompareLiteralValues(), CompareTags(ExactTags)).Expr(x, y) {
+		t.Errorf("differently spelled tags reported as equal in ExactTags mode")
+	}
+}
@@ -0,0 +1,112 @@
+package astequal
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// Bindings maps pattern metavariable names (without the $ prefix)
+// to the subtrees they matched.
+type Bindings map[string]ast.Node
+
+// Match reports whether node matches the pattern.
+//
+// The pattern is compared to node like Node does, except for
+// metavariables: identifiers that start with $.
+// A $x metavariable matches any subtree and binds it to the "x" name;
+// if $x occurs several times in the pattern, all subtrees it matches
+// must be equal (as reported by Node).
+// The $_ metavariable matches any subtree without binding it.
+// A metavariable expression statement, like $x; in a block,
+// matches any statement.
+//
+// As $x is not a valid Go identifier, patterns are usually built by
+// replacing the metavariables with some placeholder before parsing,
+// and renaming the parsed placeholder identifiers back afterwards.
+//
+// The matched subtrees are returned if node matches the pattern.
+func Match(pattern, node ast.Node) (Bindings, bool) {
+	var cmp *Comparer
+	return cmp.Match(pattern, node)
+}
+
+// Match is like Match function, but uses the Comparer configuration
+// to compare the pattern and node, and repeated metavariable subtrees.
+//
+// In the alpha-equivalence mode, metavariables at the binding sites,
+// like parameter names, are not supported.
+// In the IgnoreOrder mode, all pairings of the unordered elements
+// are tried, which takes exponential time in the worst case.
+func (cmp *Comparer) Match(pattern, node ast.Node) (Bindings, bool) {
+	c := cmp.comparer(pattern, node)
+	m := &matcher{config: c.config, binds: Bindings{}}
+	c.match = m
+	c.hooked = true
+	if name, ok := metavar(pattern); ok {
+		if !m.bind(name, node) {
+			return nil, false
+		}
+	} else if !c.astNodeEq(pattern, node) {
+		return nil, false
+	}
+	return m.binds, true
+}
+
+// matcher is a pattern matching state.
+type matcher struct {
+	config config
+	binds  Bindings
+
+	// names are the bound names in the binding order.
+	names []string
+}
+
+// bind binds the name metavariable to n.
+// It reports whether n is equal to the already bound subtree, if any.
+func (m *matcher) bind(name string, n ast.Node) bool {
+	if name == "_" {
+		return true
+	}
+	if prev, ok := m.binds[name]; ok {
+		c := newComparer(m.config)
+		return c.astNodeEq(prev, n)
+	}
+	m.binds[name] = n
+	m.names = append(m.names, name)
+	return true
+}
+
+// unbind removes the bindings made after the first n ones.
+func (m *matcher) unbind(n int) {
+	for _, name := range m.names[n:] {
+		delete(m.binds, name)
+	}
+	m.names = m.names[:n]
+}
+
+// stmtEq matches the x metavariable statement with any y statement.
+// Expression statements are matched by astExprEq,
+// so the metavariable is bound to the y expression.
+func (m *matcher) stmtEq(x, y ast.Stmt) (eq, ok bool) {
+	stmt, ok := x.(*ast.ExprStmt)
+	if !ok || stmt == nil {
+		return false, false
+	}
+	name, ok := metavar(stmt.X)
+	if !ok {
+		return false, false
+	}
+	if _, ok := y.(*ast.ExprStmt); ok {
+		return false, false
+	}
+	return m.bind(name, y), true
+}
+
+// metavar returns the n metavariable name, if n is a metavariable.
+func metavar(n ast.Node) (string, bool) {
+	id, ok := n.(*ast.Ident)
+	if !ok || id == nil || len(id.Name) < 2 || !strings.HasPrefix(id.Name, "$") {
+		return "", false
+	}
+	return id.Name[1:], true
+}
@@ -0,0 +1,172 @@
+package astequal
+
+import (
+	"go/ast"
+	"strings"
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		node    string
+		match   bool
+		binds   map[string]string
+	}{
+		{`$x`, `f(a, b)`, true, map[string]string{"x": `f(a, b)`}},
+		{`$_`, `f(a, b)`, true, map[string]string{}},
+		{`$x + 1`, `a*b + 1`, true, map[string]string{"x": `a*b`}},
+		{`$x + 1`, `a*b + 2`, false, nil},
+		{`$x + $x`, `a[i] + a[i]`, true, map[string]string{"x": `a[i]`}},
+		{`$x + $x`, `a[i] + a[j]`, false, nil},
+		{`$_ + $_`, `a[i] + a[j]`, true, map[string]string{}},
+		{`$x + $y`, `a + a`, true, map[string]string{"x": `a`, "y": `a`}},
+		{`$f($x, $x)`, `g(1, 1)`, true, map[string]string{"f": `g`, "x": `1`}},
+		{`$f($x, $x)`, `g(1, 2)`, false, nil},
+		{`$x.Close()`, `f().Close()`, true, map[string]string{"x": `f()`}},
+		{`x.$m()`, `x.Close()`, true, map[string]string{"m": `Close`}},
+		{`x.$m()`, `y.Close()`, false, nil},
+		{`len($s) == 0`, `len(xs[1:]) == 0`, true, map[string]string{"s": `xs[1:]`}},
+	}
+
+	for _, test := range tests {
+		pattern := parsePattern(test.pattern)
+		node := strparse.Expr(test.node)
+		binds, ok := Match(pattern, node)
+		if ok != test.match {
+			t.Errorf("Match(%s, %s): have %v, want %v",
+				test.pattern, test.node, ok, test.match)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if len(binds) != len(test.binds) {
+			t.Errorf("Match(%s, %s): have %d bindings, want %d",
+				test.pattern, test.node, len(binds), len(test.binds))
+		}
+		for name, want := range test.binds {
+			if !Node(binds[name], strparse.Expr(want)) {
+				t.Errorf("Match(%s, %s): $%s is not bound to %s",
+					test.pattern, test.node, name, want)
+			}
+		}
+	}
+}
+
+func TestMatchStmt(t *testing.T) {
+	tests := []struct {
+		pattern string
+		node    string
+		match   bool
+	}{
+		{`if $cond { $body }`, `if x > 0 { return x }`, true},
+		{`if $cond { $body }`, `if x > 0 { f(x) }`, true},
+		{`if $cond { $body }`, `if x > 0 { f(); g() }`, false},
+		{`{ $x; $x }`, `{ f(); f() }`, true},
+		{`{ $x; $x }`, `{ return; return }`, true},
+		{`{ $x; $x }`, `{ f(); g() }`, false},
+		{`for _, $v := range $xs { $_ }`, `for _, v := range list { use(v) }`, true},
+		{`$x = $x`, `a.b = a.b`, true},
+		{`$x = $x`, `a.b = a.c`, false},
+	}
+
+	for _, test := range tests {
+		pattern := parsePatternStmt(test.pattern)
+		node := strparse.Stmt(test.node)
+		if _, ok := Match(pattern, node); ok != test.match {
+			t.Errorf("Match(%s, %s): have %v, want %v",
+				test.pattern, test.node, ok, test.match)
+		}
+	}
+}
+
+func TestComparerMatch(t *testing.T) {
+	pattern := parsePattern(`$x + ($y)`)
+	node := strparse.Expr(`a + b`)
+	if _, ok := Match(pattern, node); ok {
+		t.Errorf("Match: parentheses are not ignored by default")
+	}
+	binds, ok := New(IgnoreParens()).Match(pattern, node)
+	if !ok {
+		t.Fatalf("Comparer.Match: node doesn't match the pattern")
+	}
+	if !Node(binds["y"], strparse.Expr(`b`)) {
+		t.Errorf("Comparer.Match: $y is not bound to b")
+	}
+}
+
+func TestComparerMatchRetry(t *testing.T) {
+	tests := []struct {
+		cmp     *Comparer
+		pattern string
+		node    string
+		binds   map[string]string
+	}{
+		{
+			New(CommutativeOperators()),
+			`$x == 1`, `1 == a`,
+			map[string]string{"x": "a"},
+		},
+		{
+			New(CommutativeOperators()),
+			`$x == $y + 1`, `1 + b == a`,
+			map[string]string{"x": "a", "y": "b"},
+		},
+		{
+			New(IgnoreOrder()),
+			`interface{ $m(); f() }`, `interface{ f(); g() }`,
+			map[string]string{"m": "g"},
+		},
+		{
+			New(IgnoreOrder()),
+			`T{a: $x, b: $x}`, `T{b: 1, a: 1}`,
+			map[string]string{"x": "1"},
+		},
+	}
+
+	for _, test := range tests {
+		binds, ok := test.cmp.Match(parsePattern(test.pattern), strparse.Expr(test.node))
+		if !ok {
+			t.Errorf("%s: %s doesn't match", test.pattern, test.node)
+			continue
+		}
+		if len(binds) != len(test.binds) {
+			t.Errorf("%s: %s: have %d bindings, want %d",
+				test.pattern, test.node, len(binds), len(test.binds))
+		}
+		for name, want := range test.binds {
+			if !Node(binds[name], strparse.Expr(want)) {
+				t.Errorf("%s: %s: $%s is not bound to %s",
+					test.pattern, test.node, name, want)
+			}
+		}
+	}
+}
+
+const patternPrefix = "__pattern_"
+
+func parsePattern(src string) ast.Expr {
+	e := strparse.Expr(strings.ReplaceAll(src, "$", patternPrefix))
+	renameMetavars(e)
+	return e
+}
+
+func parsePatternStmt(src string) ast.Stmt {
+	s := strparse.Stmt(strings.ReplaceAll(src, "$", patternPrefix))
+	renameMetavars(s)
+	return s
+}
+
+func renameMetavars(n ast.Node) {
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			if strings.HasPrefix(id.Name, patternPrefix) {
+				id.Name = "$" + strings.TrimPrefix(id.Name, patternPrefix)
+			}
+		}
+		return true
+	})
+}
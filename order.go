@@ -0,0 +1,291 @@
+package astequal
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+)
+
+// unorderedEq reports whether n x elements can be paired with n y elements,
+// so that eq(i, j) is true for every i-th x and j-th y elements pair.
+//
+// As the elements equality is an equivalence relation,
+// each x element is greedily paired with the first equal y element.
+// That's not the case for patterns, as metavariables bound by one pair
+// affect the others, so all pairings are tried when matching.
+func (c *comparer) unorderedEq(n int, eq func(i, j int) bool) bool {
+	if c.match != nil {
+		return c.pairingEq(0, make([]bool, n), eq)
+	}
+	unpaired, _ := c.pairGreedily(n, eq, false)
+	return len(unpaired) == 0
+}
+
+// pairGreedily pairs each of n x elements with the first equal y element
+// that is not used yet. It returns the unpaired x elements indexes
+// and the used y elements.
+//
+// Unless all is true, it stops at the first unpaired x element.
+func (c *comparer) pairGreedily(n int, eq func(i, j int) bool, all bool) (unpaired []int, used []bool) {
+	used = make([]bool, n)
+	for i := 0; i < n; i++ {
+		found := false
+		for j := 0; j < n && !found; j++ {
+			if used[j] {
+				continue
+			}
+			s := c.save()
+			if eq(i, j) {
+				used[j] = true
+				found = true
+			} else {
+				c.restore(s)
+			}
+		}
+		if !found {
+			unpaired = append(unpaired, i)
+			if !all {
+				break
+			}
+		}
+	}
+	return unpaired, used
+}
+
+// pairingEq reports whether x elements starting from the i-th one
+// can be paired with the y elements that are not used yet.
+func (c *comparer) pairingEq(i int, used []bool, eq func(i, j int) bool) bool {
+	if i == len(used) {
+		return true
+	}
+	for j := range used {
+		if used[j] {
+			continue
+		}
+		s := c.save()
+		if eq(i, j) {
+			used[j] = true
+			if c.pairingEq(i+1, used, eq) {
+				return true
+			}
+			used[j] = false
+		}
+		c.restore(s)
+	}
+	return false
+}
+
+func (c *comparer) unorderedSpecSliceEq(xs, ys []ast.Spec) bool {
+	return len(xs) == len(ys) && c.unorderedEq(len(xs), func(i, j int) bool {
+		return c.astSpecEq(xs[i], ys[j])
+	})
+}
+
+func (c *comparer) unorderedDeclSliceEq(xs, ys []ast.Decl) bool {
+	return len(xs) == len(ys) && c.unorderedEq(len(xs), func(i, j int) bool {
+		return c.astDeclEq(xs[i], ys[j])
+	})
+}
+
+func (c *comparer) unorderedFieldSliceEq(xs, ys []*ast.Field) bool {
+	return len(xs) == len(ys) && c.unorderedEq(len(xs), func(i, j int) bool {
+		return c.astFieldEq(xs[i], ys[j])
+	})
+}
+
+func (c *comparer) unorderedExprSliceEq(xs, ys []ast.Expr) bool {
+	return len(xs) == len(ys) && c.unorderedEq(len(xs), func(i, j int) bool {
+		return c.astExprEq(xs[i], ys[j])
+	})
+}
+
+func (c *comparer) unorderedStmtSliceEq(xs, ys []ast.Stmt) bool {
+	return len(xs) == len(ys) && c.unorderedEq(len(xs), func(i, j int) bool {
+		return c.astStmtEq(xs[i], ys[j])
+	})
+}
+
+// importDeclsEq compares the import declarations
+// that precede other file declarations.
+// The rest of the declarations are returned.
+func (c *comparer) importDeclsEq(xs, ys []ast.Decl) (bool, []ast.Decl, []ast.Decl) {
+	n, m := importDeclsLen(xs), importDeclsLen(ys)
+	ximports, yimports := xs[:n], ys[:m]
+	if c.ungroupDecls {
+		ximports, _ = ungroupDecls(ximports)
+		yimports, _ = ungroupDecls(yimports)
+	}
+	return c.unorderedDeclSliceEq(ximports, yimports), xs[n:], ys[m:]
+}
+
+func importDeclsLen(decls []ast.Decl) int {
+	for i, decl := range decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl == nil || decl.Tok != token.IMPORT {
+			return i
+		}
+	}
+	return len(decls)
+}
+
+// unorderedLit reports whether the lit elements order doesn't matter.
+// That's the case for struct literals with keyed fields.
+//
+// Without type information, any literal with identifier keys
+// is treated as a struct literal.
+func unorderedLit(info *types.Info, lit *ast.CompositeLit) bool {
+	if len(lit.Elts) == 0 {
+		return false
+	}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok || kv == nil {
+			return false
+		}
+		if _, ok := kv.Key.(*ast.Ident); !ok {
+			return false
+		}
+	}
+	if info != nil {
+		if tv, ok := info.Types[lit]; ok && tv.Type != nil {
+			_, ok := tv.Type.Underlying().(*types.Struct)
+			return ok
+		}
+	}
+	switch unparen(lit.Type).(type) {
+	case *ast.ArrayType, *ast.MapType:
+		return false
+	default:
+		return true
+	}
+}
+
+// unorderedSwitch reports whether the s clauses order doesn't matter.
+// That's the case when all case values are distinct non-boolean
+// constants and there are no fallthrough statements.
+//
+// Tagless switches are compared in order, as their boolean cases
+// may overlap, and the first matching clause wins.
+func unorderedSwitch(info *types.Info, s *ast.SwitchStmt) bool {
+	if s.Tag == nil || s.Body == nil {
+		return false
+	}
+	var values []constant.Value
+	for _, stmt := range s.Body.List {
+		clause, ok := stmt.(*ast.CaseClause)
+		if !ok || clause == nil || hasFallthrough(clause) {
+			return false
+		}
+		for _, e := range clause.List {
+			v := caseValue(info, e)
+			if v == nil {
+				return false
+			}
+			for _, prev := range values {
+				if sameConstant(prev, v) {
+					return false
+				}
+			}
+			values = append(values, v)
+		}
+	}
+	return true
+}
+
+// unorderedTypeSwitch reports whether the s clauses order doesn't matter.
+//
+// Clauses with interface case types may overlap, and the first matching
+// clause wins. So the order doesn't matter only if type information
+// shows that all case types are non-interface.
+func unorderedTypeSwitch(info *types.Info, s *ast.TypeSwitchStmt) bool {
+	if info == nil || s.Body == nil {
+		return false
+	}
+	for _, stmt := range s.Body.List {
+		clause, ok := stmt.(*ast.CaseClause)
+		if !ok || clause == nil {
+			return false
+		}
+		for _, e := range clause.List {
+			tv, ok := info.Types[e]
+			if !ok || tv.Type == nil || types.IsInterface(tv.Type) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func hasFallthrough(clause *ast.CaseClause) bool {
+	if len(clause.Body) == 0 {
+		return false
+	}
+	branch, ok := clause.Body[len(clause.Body)-1].(*ast.BranchStmt)
+	return ok && branch != nil && branch.Tok == token.FALLTHROUGH
+}
+
+// caseValue returns the e case constant value,
+// or nil if it's not a known non-boolean constant.
+//
+// Without type information, only literals with an optional sign
+// are recognized.
+func caseValue(info *types.Info, e ast.Expr) constant.Value {
+	var v constant.Value
+	if info != nil {
+		if tv, ok := info.Types[e]; ok {
+			v = tv.Value
+		}
+	} else {
+		v = literalValue(e)
+	}
+	if v == nil || v.Kind() == constant.Unknown || v.Kind() == constant.Bool {
+		return nil
+	}
+	return v
+}
+
+// literalValue returns the constant value of the e literal,
+// or nil if e is not a literal.
+func literalValue(e ast.Expr) constant.Value {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		if e == nil {
+			return nil
+		}
+		return constant.MakeFromLiteral(e.Value, e.Kind, 0)
+	case *ast.ParenExpr:
+		if e == nil {
+			return nil
+		}
+		return literalValue(e.X)
+	case *ast.UnaryExpr:
+		if e == nil || (e.Op != token.ADD && e.Op != token.SUB) {
+			return nil
+		}
+		v := literalValue(e.X)
+		if v == nil || !isNumericConstant(v) {
+			return nil
+		}
+		return constant.UnaryOp(e.Op, v, 0)
+	default:
+		return nil
+	}
+}
+
+// sameConstant reports whether x and y are the same constant.
+func sameConstant(x, y constant.Value) bool {
+	if isNumericConstant(x) && isNumericConstant(y) {
+		return constant.Compare(x, token.EQL, y)
+	}
+	return x.Kind() == y.Kind() && constant.Compare(x, token.EQL, y)
+}
+
+func isNumericConstant(v constant.Value) bool {
+	switch v.Kind() {
+	case constant.Int, constant.Float, constant.Complex:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,291 @@
+package astequal
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestIgnoreOrder(t *testing.T) {
+	tests := []struct {
+		x     string
+		y     string
+		equal bool
+	}{
+		{`interface{ A(); B() }`, `interface{ B(); A() }`, true},
+		{`interface{ io.Reader; A() }`, `interface{ A(); io.Reader }`, true},
+		{`interface{ A(); B() }`, `interface{ A(); C() }`, false},
+		{`interface{ A(); A() }`, `interface{ A(); B() }`, false},
+		{`T{A: 1, B: 2}`, `T{B: 2, A: 1}`, true},
+		{`T{A: 1, B: 2}`, `T{B: 1, A: 2}`, false},
+		{`&T{A: f(), B: T{C: 1, D: 2}}`, `&T{B: T{D: 2, C: 1}, A: f()}`, true},
+		{`T{1, 2}`, `T{2, 1}`, false},
+		{`map[string]int{a: 1, b: 2}`, `map[string]int{b: 2, a: 1}`, false},
+		{`[]int{0: 1, 1: 2}`, `[]int{1: 2, 0: 1}`, false},
+		{`struct{ A, B int }{}`, `struct{ B, A int }{}`, false},
+		{`func(a, b int) {}`, `func(b, a int) {}`, false},
+	}
+
+	cmp := New(IgnoreOrder())
+	for _, test := range tests {
+		x := strparse.Expr(test.x)
+		y := strparse.Expr(test.y)
+		if have := cmp.Expr(x, y); have != test.equal {
+			t.Errorf("Expr:\nx: %s\ny: %s\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.equal)
+		}
+	}
+}
+
+func TestIgnoreOrderStmt(t *testing.T) {
+	tests := []struct {
+		x     string
+		y     string
+		equal bool
+	}{
+		{
+			`switch x { case 1: a(); case 2, 3: b(); default: c() }`,
+			`switch x { default: c(); case 2, 3: b(); case 1: a() }`,
+			true,
+		},
+		{
+			`switch x { case 1: a(); case 2: b() }`,
+			`switch x { case 1: b(); case 2: a() }`,
+			false,
+		},
+		{
+			`switch x { case 1: a(); fallthrough; case 2: b() }`,
+			`switch x { case 2: b(); case 1: a(); fallthrough }`,
+			false,
+		},
+		{
+			`switch { case x > 0: a(); case x < 0: b() }`,
+			`switch { case x < 0: b(); case x > 0: a() }`,
+			false,
+		},
+		{
+			// Boolean cases may overlap.
+			`switch { case 1 < 2: return 1; case 3 < 4: return 2 }`,
+			`switch { case 3 < 4: return 2; case 1 < 2: return 1 }`,
+			false,
+		},
+		{
+			`switch true { case 1 < 2: return 1; case 3 < 4: return 2 }`,
+			`switch true { case 3 < 4: return 2; case 1 < 2: return 1 }`,
+			false,
+		},
+		{
+			`switch x { case 1, -1: a(); case 2: b() }`,
+			`switch x { case 2: b(); case 1, -1: a() }`,
+			true,
+		},
+		{
+			// The first one of the duplicate cases wins.
+			`switch x { case 1: a(); case 1.0: b() }`,
+			`switch x { case 1.0: b(); case 1: a() }`,
+			false,
+		},
+		{
+			`switch x { case y: a(); case z: b() }`,
+			`switch x { case z: b(); case y: a() }`,
+			false,
+		},
+		{
+			// Case types may be interfaces that match the same value.
+			`switch v := x.(type) { case int: a(v); case string: b(v) }`,
+			`switch v := x.(type) { case string: b(v); case int: a(v) }`,
+			false,
+		},
+		{
+			`switch x.(type) { case int: a(); case string: b() }`,
+			`switch x.(type) { case int: b(); case string: a() }`,
+			false,
+		},
+	}
+
+	for _, cmp := range []*Comparer{
+		New(IgnoreOrder()),
+		New(IgnoreOrder(), AlphaEquivalence()),
+	} {
+		for _, test := range tests {
+			x := strparse.Stmt(test.x)
+			y := strparse.Stmt(test.y)
+			if have := cmp.Stmt(x, y); have != test.equal {
+				t.Errorf("Stmt:\nx: %s\ny: %s\nhave: %v\nwant: %v",
+					test.x, test.y, have, test.equal)
+			}
+		}
+	}
+}
+
+func TestIgnoreOrderExplain(t *testing.T) {
+	tests := []struct {
+		x    ast.Node
+		y    ast.Node
+		want []string
+	}{
+		{
+			strparse.Expr(`interface{ A(); B(); C() }`),
+			strparse.Expr(`interface{ C(); B(); D() }`),
+			[]string{`InterfaceType.Methods.List[0].Names[0]: name mismatch`},
+		},
+		{
+			strparse.Expr(`T{A: 1, B: 2, C: 3}`),
+			strparse.Expr(`T{C: 3, A: 4, B: 2}`),
+			[]string{`CompositeLit.Elts[0].Value: value mismatch`},
+		},
+		{
+			strparse.Stmt(`switch x { case 1: a(); case 2: b() }`),
+			strparse.Stmt(`switch x { case 2: b(); case 1: c() }`),
+			[]string{`SwitchStmt.Body.List[0].Body[0].X.Fun: name mismatch`},
+		},
+		{
+			parseFile(t, "package foo\nimport (\n\"a\"\n\"b\"\n)\nvar x int"),
+			parseFile(t, "package foo\nimport (\n\"b\"\n\"a\"\n)\nvar y int"),
+			[]string{`File.Decls[1].Specs[0].Names[0]: name mismatch`},
+		},
+	}
+
+	cmp := New(IgnoreOrder())
+	for _, test := range tests {
+		var have []string
+		for _, m := range cmp.ExplainAll(test.x, test.y) {
+			have = append(have, m.String())
+		}
+		if strings.Join(have, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("ExplainAll:\nhave: %q\nwant: %q", have, test.want)
+		}
+	}
+}
+
+func TestIgnoreOrderFile(t *testing.T) {
+	tests := []struct {
+		x     string
+		y     string
+		equal bool
+	}{
+		{"import (\n\"b\"\n\"a\"\n)", "import (\n\"a\"\n\"b\"\n)", true},
+		{"import \"b\"\nimport \"a\"", "import \"a\"\nimport \"b\"", true},
+		{"import (\n\"b\"\nx \"a\"\n)", "import (\n\"a\"\nx \"b\"\n)", false},
+		{"import (\n\"b\"\n\"a\"\n)", "import \"a\"\nimport \"b\"", false},
+		{"import \"a\"\nvar x int\nvar y int", "import \"a\"\nvar y int\nvar x int", false},
+	}
+
+	cmp := New(IgnoreOrder())
+	for _, test := range tests {
+		x := parseFile(t, "package foo\n"+test.x)
+		y := parseFile(t, "package foo\n"+test.y)
+		if have := cmp.File(x, y); have != test.equal {
+			t.Errorf("File:\nx: %q\ny: %q\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.equal)
+		}
+	}
+
+	x := parseFile(t, "package foo\nimport (\n\"b\"\n\"a\"\n)")
+	y := parseFile(t, "package foo\nimport \"a\"\nimport \"b\"")
+	if !New(IgnoreOrder(), IgnoreDeclGrouping()).File(x, y) {
+		t.Errorf("regrouped and sorted imports reported as not equal")
+	}
+}
+
+func TestIgnoreOrderTypes(t *testing.T) {
+	const src = `package foo
+
+import "io"
+
+type T struct{ a, b int }
+
+type M map[string]int
+
+func f(x interface{}, a, b string) {
+	_ = T{a: 1, b: 2}
+	_ = T{b: 2, a: 1}
+	_ = M{a: 1, b: 2}
+	_ = M{b: 2, a: 1}
+	switch x.(type) {
+	case int:
+	case string:
+	}
+	switch x.(type) {
+	case string:
+	case int:
+	}
+	switch x.(type) {
+	case int:
+	case io.Reader:
+	}
+	switch x.(type) {
+	case io.Reader:
+	case int:
+	}
+	switch x.(type) {
+	case int, nil:
+	case T:
+	}
+	switch x.(type) {
+	case T:
+	case int, nil:
+	}
+	switch a {
+	case "a":
+	case b:
+	}
+	switch a {
+	case b:
+	case "a":
+	}
+	switch {
+	case 1 < 2:
+		_ = 1
+	case 3 < 4:
+		_ = 2
+	}
+	switch {
+	case 3 < 4:
+		_ = 2
+	case 1 < 2:
+		_ = 1
+	}
+	switch a {
+	case "a", "b":
+		_ = 1
+	case "c":
+		_ = 2
+	}
+	switch a {
+	case "c":
+		_ = 2
+	case "a", "b":
+		_ = 1
+	}
+}
+`
+	fset := token.NewFileSet()
+	f, info := typeCheck(t, fset, src)
+	stmts := f.Decls[len(f.Decls)-1].(*ast.FuncDecl).Body.List
+
+	tests := []struct {
+		name  string
+		i, j  int
+		equal bool
+	}{
+		{"struct literal", 0, 1, true},
+		{"map literal", 2, 3, false},
+		{"concrete type switch", 4, 5, true},
+		{"interface type switch", 6, 7, false},
+		{"concrete type switch with nil", 8, 9, true},
+		{"non-constant switch", 10, 11, false},
+		{"tagless switch", 12, 13, false},
+		{"constant switch", 14, 15, true},
+	}
+
+	cmp := New(IgnoreOrder(), WithTypesInfo(info, nil))
+	for _, test := range tests {
+		if have := cmp.Stmt(stmts[test.i], stmts[test.j]); have != test.equal {
+			t.Errorf("%s: have %v, want %v", test.name, have, test.equal)
+		}
+	}
+}
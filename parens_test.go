@@ -0,0 +1,69 @@
+package astequal
+
+import (
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestIgnoreParens(t *testing.T) {
+	tests := []struct {
+		x     string
+		y     string
+		equal bool
+	}{
+		{`(x)`, `x`, true},
+		{`((x))`, `(x)`, true},
+		{`(a*b)+c`, `a*b+c`, true},
+		{`a+(b*c)`, `a+b*c`, true},
+		{`(a+b)*c`, `a+b*c`, false},
+		{`a-(b-c)`, `a-b-c`, false},
+		{`f((x), (y))`, `f(x, y)`, true},
+		{`(f)(x)`, `f(x)`, true},
+		{`(*T)(x)`, `(*T)(x)`, true},
+		{`*T(x)`, `(*T)(x)`, false},
+		{`(<-ch)`, `<-ch`, true},
+		{`[](int){(1)}`, `[]int{1}`, true},
+		{`(x)`, `y`, false},
+	}
+
+	cmp := New(IgnoreParens())
+	for _, test := range tests {
+		x := strparse.Expr(test.x)
+		y := strparse.Expr(test.y)
+		if have := cmp.Expr(x, y); have != test.equal {
+			t.Errorf("Expr:\nx: %s\ny: %s\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.equal)
+		}
+	}
+
+	x := strparse.Stmt(`if (x > 0) { return (x) }`)
+	y := strparse.Stmt(`if x > 0 { return x }`)
+	if !cmp.Stmt(x, y) {
+		t.Errorf("statements with redundant parentheses reported as not equal")
+	}
+	if Stmt(x, y) {
+		t.Errorf("default Stmt must compare parentheses")
+	}
+}
+
+func TestIgnoreParensExplain(t *testing.T) {
+	tests := []struct {
+		x    string
+		y    string
+		want string
+	}{
+		{`(x+1)`, `x+2`, `BinaryExpr.Y: value mismatch`},
+		{`f((a), (b))`, `f(a, c)`, `CallExpr.Args[1]: name mismatch`},
+		{`((x))`, `y`, `Ident: name mismatch`},
+	}
+
+	cmp := New(IgnoreParens())
+	for _, test := range tests {
+		x := strparse.Expr(test.x)
+		y := strparse.Expr(test.y)
+		if m := cmp.Explain(x, y); m == nil || m.String() != test.want {
+			t.Errorf("Explain(%s, %s):\nhave: %v\nwant: %s", test.x, test.y, m, test.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package astequal
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+	"unicode/utf8"
+)
+
+// Report is a machine-readable comparison result.
+// It's meant to be serialized, for example, with encoding/json.
+type Report struct {
+	// Equal reports whether the nodes are equal.
+	Equal bool `json:"equal"`
+
+	// Mismatches are all differences between the nodes,
+	// as reported by ExplainAll.
+	Mismatches []ReportMismatch `json:"mismatches,omitempty"`
+}
+
+// ReportMismatch is a single Report difference.
+type ReportMismatch struct {
+	// Path is the Mismatch path.
+	Path string `json:"path"`
+
+	// Reason is the Mismatch reason name.
+	Reason string `json:"reason"`
+
+	// X and Y are the differing nodes. Nil nodes are omitted.
+	X *ReportNode `json:"x,omitempty"`
+	Y *ReportNode `json:"y,omitempty"`
+}
+
+// ReportNode describes a Report node.
+type ReportNode struct {
+	// Kind is the node type name, like "BinaryExpr".
+	Kind string `json:"kind"`
+
+	// Pos and End are the node source positions,
+	// in the file:line:column form.
+	// They are only reported with WithFileSets.
+	Pos string `json:"pos,omitempty"`
+	End string `json:"end,omitempty"`
+
+	// Snippet is the printed node.
+	// Long snippets are truncated, see MaxSnippetLen.
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// MaxSnippetLen is the maximum ReportNode snippet length, in bytes.
+// Longer snippets are truncated and end with "...".
+const MaxSnippetLen = 256
+
+// NewReport compares x and y and returns the comparison report.
+func NewReport(x, y ast.Node) *Report {
+	var cmp *Comparer
+	return cmp.Report(x, y)
+}
+
+// Report compares x and y and returns the comparison report.
+//
+// With WithFileSets, the report includes the nodes source positions.
+func (cmp *Comparer) Report(x, y ast.Node) *Report {
+	var cfg config
+	if cmp != nil {
+		cfg = cmp.config
+	}
+	mismatches := cmp.ExplainAll(x, y)
+	r := &Report{Equal: len(mismatches) == 0}
+	for _, m := range mismatches {
+		r.Mismatches = append(r.Mismatches, ReportMismatch{
+			Path:   m.Path,
+			Reason: m.Reason.String(),
+			X:      newReportNode(cfg.xfset, m.X),
+			Y:      newReportNode(cfg.yfset, m.Y),
+		})
+	}
+	return r
+}
+
+func newReportNode(fset *token.FileSet, n ast.Node) *ReportNode {
+	if isNilNode(n) {
+		return nil
+	}
+	rn := &ReportNode{Kind: nodeTypeName(n)}
+	withPos := fset != nil && n.Pos().IsValid()
+	if withPos {
+		rn.Pos = fset.Position(n.Pos()).String()
+		rn.End = fset.Position(n.End()).String()
+	} else {
+		fset = token.NewFileSet()
+	}
+	if text, err := printNode(fset, n, withPos); err == nil {
+		rn.Snippet = truncate(strings.TrimSuffix(text, "\n"), MaxSnippetLen)
+	}
+	return rn
+}
+
+// truncate returns s truncated to at most n bytes,
+// without splitting UTF-8 sequences.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	const ellipsis = "..."
+	cut := n - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + ellipsis
+}
@@ -0,0 +1,112 @@
+package astequal
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestExplainAll(t *testing.T) {
+	tests := []struct {
+		x     string
+		y     string
+		paths []string
+	}{
+		{`f(x, y)`, `f(x, y)`, nil},
+		{`f(x, y)`, `g(x, z)`, []string{
+			"CallExpr.Fun: name mismatch",
+			"CallExpr.Args[1]: name mismatch",
+		}},
+		{`a + b*c`, `a - b*d`, []string{
+			"BinaryExpr: operator mismatch",
+		}},
+		{`[]int{1, 2}`, `[]string{1, 3}`, []string{
+			"CompositeLit.Type.Elt: name mismatch",
+			"CompositeLit.Elts[1]: value mismatch",
+		}},
+		{`f(x, y)`, `g(x)`, []string{
+			"CallExpr.Fun: name mismatch",
+			"CallExpr.Args: length mismatch",
+		}},
+	}
+
+	for _, test := range tests {
+		x := strparse.Expr(test.x)
+		y := strparse.Expr(test.y)
+		var have []string
+		for _, m := range ExplainAll(x, y) {
+			have = append(have, m.String())
+		}
+		if strings.Join(have, "\n") != strings.Join(test.paths, "\n") {
+			t.Errorf("ExplainAll:\nx: %s\ny: %s\nhave:\n\t%s\nwant:\n\t%s",
+				test.x, test.y, strings.Join(have, "\n\t"), strings.Join(test.paths, "\n\t"))
+		}
+		if m := Explain(x, y); m != nil && m.String() != have[0] {
+			t.Errorf("Explain and ExplainAll disagree: %s vs %s", m, have[0])
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	fset := token.NewFileSet()
+	x, err := parser.ParseExprFrom(fset, "x.go", `f(a, "b")`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := parser.ParseExprFrom(fset, "y.go", `f(a, "c", d)`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(New(WithFileSets(fset, nil)).Report(x, y))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"equal":false,"mismatches":[{"path":"CallExpr.Args","reason":"length mismatch",` +
+		`"x":{"kind":"CallExpr","pos":"x.go:1:1","end":"x.go:1:10","snippet":"f(a, \"b\")"},` +
+		`"y":{"kind":"CallExpr","pos":"y.go:1:1","end":"y.go:1:13","snippet":"f(a, \"c\", d)"}}]}`
+	if string(data) != want {
+		t.Errorf("Report:\nhave: %s\nwant: %s", data, want)
+	}
+
+	data, err = json.Marshal(NewReport(x, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"equal":false,"mismatches":[{"path":"CallExpr","reason":"nil mismatch",` +
+		`"x":{"kind":"CallExpr","snippet":"f(a, \"b\")"}}]}`
+	if string(data) != want {
+		t.Errorf("Report:\nhave: %s\nwant: %s", data, want)
+	}
+
+	data, err = json.Marshal(NewReport(x, x))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"equal":true}`; string(data) != want {
+		t.Errorf("Report:\nhave: %s\nwant: %s", data, want)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"abc", 3, "abc"},
+		{"abcdef", 5, "ab..."},
+		{"abéé", 6, "abéé"},
+		{"aébcd", 5, "a..."},
+		{"ééé", 5, "é..."},
+	}
+	for _, test := range tests {
+		if have := truncate(test.s, test.n); have != test.want {
+			t.Errorf("truncate(%q, %d): have %q, want %q", test.s, test.n, have, test.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package astequal
+
+import (
+	"go/ast"
+	"strconv"
+)
+
+// TagMode controls how struct field tags are compared.
+type TagMode int
+
+const (
+	// IgnoreTags makes field tags irrelevant for the comparison.
+	// This is the default mode.
+	IgnoreTags TagMode = iota
+
+	// ExactTags requires field tags to be spelled identically.
+	ExactTags
+
+	// SemanticTags compares field tags the way reflect.StructTag sees them:
+	// as a set of key:"value" pairs. Key order, spacing and the quoting style
+	// of the tag literal (raw or interpreted) are irrelevant.
+	//
+	// Tags that are not in the conventional format are compared
+	// by their (unquoted) string values.
+	SemanticTags
+)
+
+// tagsEq compares x and y field tags according to the tags mode.
+func (c *comparer) tagsEq(x, y *ast.BasicLit) bool {
+	switch c.tags {
+	case ExactTags:
+		if x == nil || y == nil {
+			return x == y
+		}
+		return x.Value == y.Value
+	case SemanticTags:
+		return structTagEq(x, y)
+	default:
+		return true
+	}
+}
+
+// structTagEq reports whether x and y tags are equivalent
+// for the reflect.StructTag.Lookup.
+//
+// Missing tag is equivalent to the empty one.
+func structTagEq(x, y *ast.BasicLit) bool {
+	xtag, ok1 := tagValue(x)
+	ytag, ok2 := tagValue(y)
+	if !ok1 || !ok2 {
+		return ok1 == ok2 && x.Value == y.Value
+	}
+	if xtag == ytag {
+		return true
+	}
+
+	xpairs, ok1 := parseStructTag(xtag)
+	ypairs, ok2 := parseStructTag(ytag)
+	if !ok1 || !ok2 || len(xpairs) != len(ypairs) {
+		return false
+	}
+	for k, v := range xpairs {
+		if yv, ok := ypairs[k]; !ok || yv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// tagValue returns the unquoted value of a tag literal.
+func tagValue(tag *ast.BasicLit) (string, bool) {
+	if tag == nil {
+		return "", true
+	}
+	s, err := strconv.Unquote(tag.Value)
+	return s, err == nil
+}
+
+// parseStructTag parses tag into key-value pairs.
+// It follows the reflect.StructTag.Lookup parsing rules:
+// if a key is repeated, the first value wins.
+//
+// Reports false if tag is not in the conventional format.
+func parseStructTag(tag string) (map[string]string, bool) {
+	pairs := make(map[string]string)
+	for {
+		// Skip leading space.
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			return pairs, true
+		}
+
+		// Scan to colon. A space, a quote or a control character is a syntax error.
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			return nil, false
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		// Scan quoted string to find value.
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return nil, false
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			return nil, false
+		}
+		tag = tag[i+1:]
+
+		if _, ok := pairs[key]; !ok {
+			pairs[key] = value
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package astequal
+
+import (
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestCompareTags(t *testing.T) {
+	tests := []struct {
+		x string
+		y string
+
+		ignore   bool
+		exact    bool
+		semantic bool
+	}{
+		{"struct{A int}", "struct{A int}", true, true, true},
+		{"struct{A int `json:\"a\"`}", "struct{A int `json:\"a\"`}", true, true, true},
+		{"struct{A int `json:\"a\"`}", "struct{A int `json:\"b\"`}", true, false, false},
+		{"struct{A int `json:\"a\"`}", "struct{A int `yaml:\"a\"`}", true, false, false},
+		{"struct{A int `json:\"a\"`}", "struct{A int}", true, false, false},
+		{"struct{A int ``}", "struct{A int}", true, false, true},
+		{"struct{A int `json:\"a\"`}", `struct{A int "json:\"a\""}`, true, false, true},
+		{"struct{A int `json:\"a\" yaml:\"b\"`}", "struct{A int `yaml:\"b\" json:\"a\"`}", true, false, true},
+		{"struct{A int `json:\"a\" yaml:\"b\"`}", "struct{A int `json:\"a\"   yaml:\"b\"`}", true, false, true},
+		{"struct{A int `json:\"a\" yaml:\"b\"`}", "struct{A int `json:\"a\"`}", true, false, false},
+		{"struct{A int `json:\"a\" json:\"b\"`}", "struct{A int `json:\"a\"`}", true, false, true},
+		{"struct{A int `json:\"\\x61\"`}", "struct{A int `json:\"a\"`}", true, false, true},
+		{"struct{A int `json:\"a,omitempty\"`}", "struct{A int `json:\"a\"`}", true, false, false},
+		{"struct{A int `invalid`}", "struct{A int `invalid`}", true, true, true},
+		{"struct{A int `invalid`}", `struct{A int "invalid"}`, true, false, true},
+		{"struct{A int `invalid`}", "struct{A int `invalid `}", true, false, false},
+		{"struct{A int `json:\"a\"`}", "struct{B int `json:\"a\"`}", false, false, false},
+	}
+
+	ignore := New()
+	exact := New(CompareTags(ExactTags))
+	semantic := New(CompareTags(SemanticTags))
+	for _, test := range tests {
+		x := strparse.Expr(test.x)
+		y := strparse.Expr(test.y)
+		if have := ignore.Expr(x, y); have != test.ignore {
+			t.Errorf("IgnoreTags:\nx: %s\ny: %s\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.ignore)
+		}
+		if have := exact.Expr(x, y); have != test.exact {
+			t.Errorf("ExactTags:\nx: %s\ny: %s\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.exact)
+		}
+		if have := semantic.Expr(x, y); have != test.semantic {
+			t.Errorf("SemanticTags:\nx: %s\ny: %s\nhave: %v\nwant: %v",
+				test.x, test.y, have, test.semantic)
+		}
+	}
+}
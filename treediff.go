@@ -0,0 +1,583 @@
+package astequal
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+)
+
+// EditKind is a tree edit operation kind.
+type EditKind int
+
+const (
+	// Insert is an insertion of a y subtree.
+	Insert EditKind = iota + 1
+
+	// Delete is a deletion of an x subtree.
+	Delete
+
+	// Update is a change of an x node own value,
+	// like an identifier name or an operator.
+	Update
+
+	// Move is a relocation of an x subtree
+	// to another parent or position.
+	Move
+)
+
+var editKindNames = [...]string{
+	Insert: "insert",
+	Delete: "delete",
+	Update: "update",
+	Move:   "move",
+}
+
+func (k EditKind) String() string {
+	if k > 0 && int(k) < len(editKindNames) {
+		return editKindNames[k]
+	}
+	return fmt.Sprintf("EditKind(%d)", int(k))
+}
+
+// Edit is a single tree edit operation.
+type Edit struct {
+	Kind EditKind
+
+	// X is the x node. It's nil for insertions.
+	X ast.Node
+
+	// Y is the y node. It's nil for deletions.
+	// For updates and moves, it's the node X is matched with.
+	Y ast.Node
+
+	// Parent is the y parent of the inserted or moved node,
+	// or the x parent of the deleted node.
+	// It's nil for updates and for the tree roots.
+	Parent ast.Node
+}
+
+func (e Edit) String() string {
+	switch e.Kind {
+	case Insert:
+		return fmt.Sprintf("insert %s into %s", nodeTypeName(e.Y), nodeTypeName(e.Parent))
+	case Delete:
+		return fmt.Sprintf("delete %s from %s", nodeTypeName(e.X), nodeTypeName(e.Parent))
+	case Update:
+		return fmt.Sprintf("update %s %s to %s", nodeTypeName(e.X), nodeLabel(e.X), nodeLabel(e.Y))
+	case Move:
+		return fmt.Sprintf("move %s into %s", nodeTypeName(e.X), nodeTypeName(e.Parent))
+	default:
+		return e.Kind.String()
+	}
+}
+
+// TreeDiff returns an edit script that turns the x tree into the y tree.
+//
+// The x and y nodes are matched GumTree-style: first the biggest equal
+// subtrees are matched top-down, then the nodes that contain
+// enough matched descendants are matched bottom-up.
+// The edit script is built from the matching:
+//
+//   - matched nodes with a different own value (see Update) are updated;
+//   - matched nodes that changed their parent or their position among
+//     the matched siblings are moved;
+//   - unmatched x subtrees are deleted;
+//   - unmatched y subtrees are inserted.
+//
+// Inserted and deleted subtrees are reported by their roots.
+// Deletions, updates and moves come in the x source order,
+// followed by insertions in the y source order.
+// Comments are ignored.
+func TreeDiff(x, y ast.Node) []Edit {
+	if isNilNode(x) && isNilNode(y) {
+		return nil
+	}
+	var xt, yt *tnode
+	if !isNilNode(x) {
+		xt = buildTree(x, nil, new(int))
+	}
+	if !isNilNode(y) {
+		yt = buildTree(y, nil, new(int))
+	}
+	if xt == nil || yt == nil {
+		if xt == nil {
+			return []Edit{{Kind: Insert, Y: y}}
+		}
+		return []Edit{{Kind: Delete, X: x}}
+	}
+
+	matchTopDown(xt, yt)
+	matchBottomUp(xt, yt)
+	return editScript(xt, yt)
+}
+
+// tnode is a tree node with the matching-related properties.
+type tnode struct {
+	node     ast.Node
+	parent   *tnode
+	children []*tnode
+
+	label  string
+	hash   uint64
+	height int // 1 for leaves
+	size   int // number of nodes in the subtree
+	pre    int // pre-order index
+
+	match *tnode
+}
+
+func buildTree(n ast.Node, parent *tnode, pre *int) *tnode {
+	t := &tnode{node: n, parent: parent, label: nodeLabel(n), pre: *pre, height: 1, size: 1}
+	*pre++
+	h := hasher{sum: fnvOffset64}
+	h.string(nodeTypeName(n))
+	h.string(t.label)
+	for _, c := range childNodes(n) {
+		child := buildTree(c, t, pre)
+		t.children = append(t.children, child)
+		if child.height+1 > t.height {
+			t.height = child.height + 1
+		}
+		t.size += child.size
+		h.int(int(child.hash))
+	}
+	t.hash = h.sum
+	return t
+}
+
+// childNodes returns the n direct children, except for comments.
+func childNodes(n ast.Node) []ast.Node {
+	var list []ast.Node
+	ast.Inspect(n, func(c ast.Node) bool {
+		switch c.(type) {
+		case nil, *ast.Comment, *ast.CommentGroup:
+			return false
+		}
+		if c == n {
+			return true
+		}
+		list = append(list, c)
+		return false
+	})
+	return list
+}
+
+// nodeLabel returns the n own value, which is not represented by
+// its children, like an identifier name or an operator.
+func nodeLabel(n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.Ident:
+		return n.Name
+	case *ast.BasicLit:
+		return n.Value
+	case *ast.BinaryExpr:
+		return n.Op.String()
+	case *ast.UnaryExpr:
+		return n.Op.String()
+	case *ast.AssignStmt:
+		return n.Tok.String()
+	case *ast.IncDecStmt:
+		return n.Tok.String()
+	case *ast.BranchStmt:
+		return n.Tok.String()
+	case *ast.GenDecl:
+		return n.Tok.String()
+	case *ast.RangeStmt:
+		return n.Tok.String()
+	case *ast.ChanType:
+		switch n.Dir {
+		case ast.SEND:
+			return "chan<-"
+		case ast.RECV:
+			return "<-chan"
+		default:
+			return "chan"
+		}
+	case *ast.SliceExpr:
+		if n.Slice3 {
+			return "[::]"
+		}
+		return "[:]"
+	default:
+		return ""
+	}
+}
+
+func (t *tnode) isLeaf() bool { return len(t.children) == 0 }
+
+// contains reports whether u is a t descendant (or t itself).
+func (t *tnode) contains(u *tnode) bool {
+	return t.pre <= u.pre && u.pre < t.pre+t.size
+}
+
+// isomorphic reports whether x and y subtrees are equal.
+func isomorphic(x, y *tnode) bool {
+	if x.hash != y.hash || x.size != y.size || x.label != y.label ||
+		len(x.children) != len(y.children) ||
+		nodeTagOf(x.node) != nodeTagOf(y.node) {
+		return false
+	}
+	for i := range x.children {
+		if !isomorphic(x.children[i], y.children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	// minHeight is the minimal height of subtrees matched top-down.
+	minHeight = 2
+
+	// minDice is the minimal ratio of common descendants
+	// for nodes matched bottom-up.
+	minDice = 0.5
+)
+
+func link(x, y *tnode) {
+	x.match = y
+	y.match = x
+}
+
+// linkSubtrees matches the isomorphic x and y subtrees.
+func linkSubtrees(x, y *tnode) {
+	link(x, y)
+	for i := range x.children {
+		linkSubtrees(x.children[i], y.children[i])
+	}
+}
+
+// matchTopDown matches the biggest isomorphic subtrees.
+func matchTopDown(xt, yt *tnode) {
+	xs, ys := []*tnode{xt}, []*tnode{yt}
+	type candidate struct {
+		x, y *tnode
+	}
+	var ambiguous []candidate
+
+	popHeight := func(list []*tnode, h int) (popped, rest []*tnode) {
+		for _, t := range list {
+			if t.height == h {
+				popped = append(popped, t)
+			} else {
+				rest = append(rest, t)
+			}
+		}
+		return popped, rest
+	}
+	open := func(list []*tnode, ts []*tnode) []*tnode {
+		for _, t := range ts {
+			list = append(list, t.children...)
+		}
+		return list
+	}
+
+	for len(xs) != 0 && len(ys) != 0 {
+		hx, hy := maxHeight(xs), maxHeight(ys)
+		if hx < minHeight && hy < minHeight {
+			break
+		}
+		if hx != hy {
+			if hx > hy {
+				var popped []*tnode
+				popped, xs = popHeight(xs, hx)
+				xs = open(xs, popped)
+			} else {
+				var popped []*tnode
+				popped, ys = popHeight(ys, hy)
+				ys = open(ys, popped)
+			}
+			continue
+		}
+
+		var px, py []*tnode
+		px, xs = popHeight(xs, hx)
+		py, ys = popHeight(ys, hy)
+		xcount := make(map[*tnode]int)
+		ycount := make(map[*tnode]int)
+		var pairs []candidate
+		for _, x := range px {
+			for _, y := range py {
+				if isomorphic(x, y) {
+					pairs = append(pairs, candidate{x, y})
+					xcount[x]++
+					ycount[y]++
+				}
+			}
+		}
+		for _, p := range pairs {
+			if xcount[p.x] == 1 && ycount[p.y] == 1 {
+				linkSubtrees(p.x, p.y)
+			} else {
+				ambiguous = append(ambiguous, p)
+			}
+		}
+		for _, x := range px {
+			if xcount[x] == 0 {
+				xs = append(xs, x.children...)
+			}
+		}
+		for _, y := range py {
+			if ycount[y] == 0 {
+				ys = append(ys, y.children...)
+			}
+		}
+	}
+
+	// Ambiguous candidates with more similar parents go first.
+	sort.SliceStable(ambiguous, func(i, j int) bool {
+		return parentDice(ambiguous[i].x, ambiguous[i].y) >
+			parentDice(ambiguous[j].x, ambiguous[j].y)
+	})
+	for _, p := range ambiguous {
+		if p.x.match == nil && p.y.match == nil {
+			linkSubtrees(p.x, p.y)
+		}
+	}
+}
+
+func maxHeight(list []*tnode) int {
+	h := 0
+	for _, t := range list {
+		if t.height > h {
+			h = t.height
+		}
+	}
+	return h
+}
+
+func parentDice(x, y *tnode) float64 {
+	if x.parent == nil || y.parent == nil {
+		return 0
+	}
+	return dice(x.parent, y.parent)
+}
+
+// dice returns the ratio of x and y common descendants,
+// which are matched with each other.
+func dice(x, y *tnode) float64 {
+	if x.size+y.size == 2 {
+		return 0
+	}
+	common := 0
+	var walk func(t *tnode)
+	walk = func(t *tnode) {
+		for _, c := range t.children {
+			if c.match != nil && y.contains(c.match) && c.match != y {
+				common++
+			}
+			walk(c)
+		}
+	}
+	walk(x)
+	return 2 * float64(common) / float64(x.size+y.size-2)
+}
+
+// matchBottomUp matches the nodes that have enough matched descendants.
+func matchBottomUp(xt, yt *tnode) {
+	var walk func(x *tnode)
+	walk = func(x *tnode) {
+		for _, c := range x.children {
+			walk(c)
+		}
+		if x.match != nil || x.isLeaf() {
+			return
+		}
+		var best *tnode
+		bestDice := 0.0
+		for _, y := range candidates(x, yt) {
+			if d := dice(x, y); d > bestDice {
+				best, bestDice = y, d
+			}
+		}
+		if best != nil && bestDice >= minDice {
+			link(x, best)
+			recoverChildren(x, best)
+		}
+	}
+	walk(xt)
+
+	if xt.match == nil && yt.match == nil && nodeTagOf(xt.node) == nodeTagOf(yt.node) {
+		link(xt, yt)
+		recoverChildren(xt, yt)
+	}
+}
+
+// candidates returns the unmatched y nodes of the x kind
+// that contain y nodes matched with the x descendants.
+func candidates(x, yt *tnode) []*tnode {
+	tag := nodeTagOf(x.node)
+	seen := make(map[*tnode]bool)
+	var list []*tnode
+	var walk func(t *tnode)
+	walk = func(t *tnode) {
+		for _, c := range t.children {
+			if c.match != nil {
+				for y := c.match.parent; y != nil && !seen[y]; y = y.parent {
+					seen[y] = true
+					if y.match == nil && nodeTagOf(y.node) == tag {
+						list = append(list, y)
+					}
+				}
+			}
+			walk(c)
+		}
+	}
+	walk(x)
+	return list
+}
+
+// recoverChildren matches the unmatched children of the matched x and y nodes:
+// isomorphic children are matched first, then the children
+// that are the only unmatched ones of their kind on both sides.
+func recoverChildren(x, y *tnode) {
+	for _, xc := range x.children {
+		if xc.match != nil {
+			continue
+		}
+		for _, yc := range y.children {
+			if yc.match == nil && isomorphic(xc, yc) {
+				linkSubtrees(xc, yc)
+				break
+			}
+		}
+	}
+
+	unmatched := func(t *tnode) map[nodeTag][]*tnode {
+		kinds := make(map[nodeTag][]*tnode)
+		for _, c := range t.children {
+			if c.match == nil {
+				tag := nodeTagOf(c.node)
+				kinds[tag] = append(kinds[tag], c)
+			}
+		}
+		return kinds
+	}
+	ykinds := unmatched(y)
+	for tag, xcs := range unmatched(x) {
+		ycs := ykinds[tag]
+		if len(xcs) == 1 && len(ycs) == 1 {
+			link(xcs[0], ycs[0])
+			recoverChildren(xcs[0], ycs[0])
+		}
+	}
+}
+
+// editScript builds the edit script from the matching.
+func editScript(xt, yt *tnode) []Edit {
+	var edits []Edit
+
+	// Matched siblings that keep their relative order.
+	inOrder := make(map[*tnode]bool)
+
+	var walkX func(x *tnode)
+	walkX = func(x *tnode) {
+		switch {
+		case x.match == nil:
+			var parent ast.Node
+			if x.parent != nil {
+				parent = x.parent.node
+			}
+			edits = append(edits, Edit{Kind: Delete, X: x.node, Parent: parent})
+		default:
+			y := x.match
+			if x.label != y.label {
+				edits = append(edits, Edit{Kind: Update, X: x.node, Y: y.node})
+			}
+			if x.parent != nil && y.parent != nil &&
+				(x.parent.match != y.parent || !inOrder[x]) {
+				edits = append(edits, Edit{Kind: Move, X: x.node, Y: y.node, Parent: y.parent.node})
+			}
+			for _, c := range alignChildren(x, y) {
+				inOrder[c] = true
+			}
+		}
+		for _, c := range x.children {
+			if x.match == nil && c.match == nil {
+				// Covered by the x deletion,
+				// but there may be moved nodes inside.
+				walkDeleted(c, func(t *tnode) { walkX(t) })
+				continue
+			}
+			walkX(c)
+		}
+	}
+	walkX(xt)
+
+	var walkY func(y *tnode)
+	walkY = func(y *tnode) {
+		if y.match == nil {
+			var parent ast.Node
+			if y.parent != nil {
+				parent = y.parent.node
+			}
+			edits = append(edits, Edit{Kind: Insert, Y: y.node, Parent: parent})
+			return
+		}
+		for _, c := range y.children {
+			walkY(c)
+		}
+	}
+	walkY(yt)
+
+	return edits
+}
+
+// walkDeleted calls visit for the matched nodes inside
+// the unmatched t subtree.
+func walkDeleted(t *tnode, visit func(*tnode)) {
+	if t.match != nil {
+		visit(t)
+		return
+	}
+	for _, c := range t.children {
+		walkDeleted(c, visit)
+	}
+}
+
+// alignChildren returns the x children that are matched with the y children
+// and keep their relative order, as the longest common subsequence.
+func alignChildren(x, y *tnode) []*tnode {
+	var xs, ys []*tnode
+	for _, c := range x.children {
+		if c.match != nil && c.match.parent == y {
+			xs = append(xs, c)
+		}
+	}
+	for _, c := range y.children {
+		if c.match != nil && c.match.parent == x {
+			ys = append(ys, c)
+		}
+	}
+	n, k := len(xs), len(ys)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, k+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := k - 1; j >= 0; j-- {
+			switch {
+			case xs[i].match == ys[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var list []*tnode
+	for i, j := 0, 0; i < n && j < k; {
+		switch {
+		case xs[i].match == ys[j]:
+			list = append(list, xs[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return list
+}
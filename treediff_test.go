@@ -0,0 +1,116 @@
+package astequal
+
+import (
+	"go/ast"
+	"strings"
+	"testing"
+
+	"github.com/go-toolsmith/strparse"
+)
+
+func TestTreeDiff(t *testing.T) {
+	tests := []struct {
+		x     string
+		y     string
+		edits []string
+	}{
+		{
+			`{ x := a + b; f(x) }`,
+			`{ x := a + b; f(x) }`,
+			nil,
+		},
+		{
+			`{ x := a + b; f(x) }`,
+			`{ x := a + c; f(x) }`,
+			[]string{"update Ident b to c"},
+		},
+		{
+			`{ x := a + b; f(x) }`,
+			`{ x := a - b; f(x) }`,
+			[]string{"update BinaryExpr + to -"},
+		},
+		{
+			`{ a(1); b(2) }`,
+			`{ a(1); c(3); b(2) }`,
+			[]string{"insert ExprStmt into BlockStmt"},
+		},
+		{
+			`{ a(1); c(3); b(2) }`,
+			`{ a(1); b(2) }`,
+			[]string{"delete ExprStmt from BlockStmt"},
+		},
+		{
+			`{ a(1); b(2); c(3) }`,
+			`{ b(2); c(3); a(1) }`,
+			[]string{"move ExprStmt into BlockStmt"},
+		},
+		{
+			`{ if c { f(1) } else { g(2) }; h(x, y) }`,
+			`{ if c { f(1) } else { g(2); h(x, y) } }`,
+			[]string{"move ExprStmt into BlockStmt"},
+		},
+		{
+			`{ for _, x := range xs { use(x, 1) } }`,
+			`{ for _, x := range xs { if x > 0 { use(x, 1) } } }`,
+			// The whole loop body is matched with the if body.
+			[]string{"move BlockStmt into IfStmt", "insert BlockStmt into RangeStmt"},
+		},
+	}
+
+	for _, test := range tests {
+		x := strparse.Stmt(test.x)
+		y := strparse.Stmt(test.y)
+		edits := TreeDiff(x, y)
+		var have []string
+		for _, e := range edits {
+			have = append(have, e.String())
+		}
+		if strings.Join(have, "\n") != strings.Join(test.edits, "\n") {
+			t.Errorf("TreeDiff:\nx: %s\ny: %s\nhave:\n\t%s\nwant:\n\t%s",
+				test.x, test.y, strings.Join(have, "\n\t"), strings.Join(test.edits, "\n\t"))
+		}
+	}
+}
+
+func TestTreeDiffEdits(t *testing.T) {
+	x := strparse.Stmt(`{ if c { f(1) } else { g(2) }; h(x, y) }`)
+	y := strparse.Stmt(`{ if c { f(1) } else { g(2); h(x, y) } }`)
+	edits := TreeDiff(x, y)
+	if len(edits) != 1 {
+		t.Fatalf("have %d edits, want 1", len(edits))
+	}
+	e := edits[0]
+	xblock := x.(*ast.BlockStmt)
+	yelse := y.(*ast.BlockStmt).List[0].(*ast.IfStmt).Else.(*ast.BlockStmt)
+	if e.X != xblock.List[1] || e.Y != yelse.List[1] || e.Parent != yelse {
+		t.Errorf("move edit nodes are not the moved statement and its new parent")
+	}
+
+	edits = TreeDiff(nil, y)
+	if len(edits) != 1 || edits[0].Kind != Insert || edits[0].Y != y {
+		t.Errorf("TreeDiff(nil, y) is not an y insertion")
+	}
+	edits = TreeDiff(x, nil)
+	if len(edits) != 1 || edits[0].Kind != Delete || edits[0].X != x {
+		t.Errorf("TreeDiff(x, nil) is not an x deletion")
+	}
+	if edits := TreeDiff(nil, nil); edits != nil {
+		t.Errorf("TreeDiff(nil, nil): have %d edits, want none", len(edits))
+	}
+}
+
+func TestTreeDiffSamples(t *testing.T) {
+	nodes := sampleNodes(t)
+	for _, x := range nodes {
+		if edits := TreeDiff(x, x); len(edits) != 0 {
+			t.Errorf("TreeDiff(x, x) of %T: have %d edits, want none", x, len(edits))
+		}
+		for _, y := range nodes {
+			for _, e := range TreeDiff(x, y) {
+				if e.Kind == Update && nodeLabel(e.X) == nodeLabel(e.Y) {
+					t.Errorf("update of %T with the same value", e.X)
+				}
+			}
+		}
+	}
+}
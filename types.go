@@ -0,0 +1,161 @@
+package astequal
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+)
+
+// objectEq compares x and y identifiers by the objects they refer to.
+//
+// Reports false as the second result if any of the identifiers
+// is not resolved, so it should be compared by its name.
+func (c *comparer) objectEq(x, y *ast.Ident) (equal, ok bool) {
+	if c.xinfo != c.yinfo {
+		c.pairObjects(c.xinfo.Defs[x], c.yinfo.Defs[y])
+	}
+	xobj := c.xinfo.Uses[x]
+	yobj := c.yinfo.Uses[y]
+	if xobj == nil || yobj == nil {
+		return false, false
+	}
+	return c.sameObject(xobj, yobj), true
+}
+
+// qualifiedEq compares an identifier and a qualified identifier,
+// like Join (imported with a dot import) and strings.Join.
+func (c *comparer) qualifiedEq(x, y ast.Expr) bool {
+	xobj := c.qualifiedObject(c.xinfo, x)
+	yobj := c.qualifiedObject(c.yinfo, y)
+	return xobj != nil && yobj != nil && c.sameObject(xobj, yobj)
+}
+
+// qualifiedObject returns the object denoted by the package-level
+// identifier or the qualified identifier e.
+func (c *comparer) qualifiedObject(info *types.Info, e ast.Expr) types.Object {
+	switch e := e.(type) {
+	case *ast.Ident:
+		obj := info.Uses[e]
+		if obj == nil || !isPackageLevel(obj) {
+			return nil
+		}
+		return obj
+	case *ast.SelectorExpr:
+		pkg, ok := e.X.(*ast.Ident)
+		if !ok || e.Sel == nil {
+			return nil
+		}
+		if _, ok := info.Uses[pkg].(*types.PkgName); !ok {
+			return nil
+		}
+		return info.Uses[e.Sel]
+	default:
+		return nil
+	}
+}
+
+// sameObject reports whether x and y denote the same entity.
+//
+// Objects that come from different type checks can't be compared
+// by their identity. They are matched by the kind, package and name
+// for the package-level objects, by the full name for methods and
+// by the type for struct fields. Local objects of different type checks
+// are matched by localEq.
+func (c *comparer) sameObject(x, y types.Object) bool {
+	if x == y {
+		return true
+	}
+
+	// Different imports of the same package.
+	if x, ok := x.(*types.PkgName); ok {
+		y, ok := y.(*types.PkgName)
+		return ok && x.Imported().Path() == y.Imported().Path()
+	}
+
+	if c.xinfo == c.yinfo {
+		return false
+	}
+	if reflect.TypeOf(x) != reflect.TypeOf(y) ||
+		x.Name() != y.Name() ||
+		objectPkgPath(x) != objectPkgPath(y) ||
+		isPackageLevel(x) != isPackageLevel(y) {
+		return false
+	}
+	if isPackageLevel(x) {
+		return true
+	}
+
+	switch x := x.(type) {
+	case *types.Func:
+		return x.FullName() == y.(*types.Func).FullName()
+	case *types.Var:
+		y := y.(*types.Var)
+		if x.IsField() || y.IsField() {
+			return x.IsField() == y.IsField() &&
+				types.TypeString(x.Type(), nil) == types.TypeString(y.Type(), nil)
+		}
+	}
+	return c.localEq(x, y)
+}
+
+// objectPair is a pair of local objects declared by the compared nodes.
+type objectPair struct {
+	x types.Object
+	y types.Object
+}
+
+// pairObjects records x and y as objects declared by the same
+// declaration of the compared nodes.
+func (c *comparer) pairObjects(x, y types.Object) {
+	if x == nil || y == nil || isPackageLevel(x) || isPackageLevel(y) {
+		return
+	}
+	c.objects = append(c.objects, objectPair{x: x, y: y})
+}
+
+// localEq reports whether x and y local objects of different
+// type checks denote the same entity.
+//
+// Objects declared within the compared nodes are matched by their
+// declarations. Objects declared outside of them are matched by
+// their declarations offsets relative to the compared nodes,
+// so they only match if the code around the nodes is laid out
+// the same way.
+func (c *comparer) localEq(x, y types.Object) bool {
+	for i := len(c.objects) - 1; i >= 0; i-- {
+		p := c.objects[i]
+		if p.x == x || p.y == y {
+			return p.x == x && p.y == y
+		}
+	}
+	if !c.xroot.IsValid() || !c.yroot.IsValid() {
+		return true
+	}
+	return x.Pos()-c.xroot == y.Pos()-c.yroot
+}
+
+// rootPos returns the position of the compared root node,
+// or token.NoPos if it's nil.
+func rootPos(n ast.Node) token.Pos {
+	if isNilNode(n) {
+		return token.NoPos
+	}
+	return n.Pos()
+}
+
+// isPackageLevel reports whether obj is declared in the package
+// or in the universe scope.
+func isPackageLevel(obj types.Object) bool {
+	if obj.Pkg() == nil {
+		return obj.Parent() == types.Universe
+	}
+	return obj.Parent() != nil && obj.Parent() == obj.Pkg().Scope()
+}
+
+func objectPkgPath(obj types.Object) string {
+	if obj.Pkg() == nil {
+		return ""
+	}
+	return obj.Pkg().Path()
+}
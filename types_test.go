@@ -0,0 +1,225 @@
+package astequal
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+func TestWithTypesInfo(t *testing.T) {
+	const src = `package foo
+
+import (
+	"strings"
+	strs "strings"
+	. "strings"
+)
+
+type T struct{ f int }
+
+func (T) m() {}
+
+var g = 1
+
+func f(a int, xs []string) {
+	_ = strings.Join(xs, "")
+	_ = strs.Join(xs, "")
+	_ = Join(xs, "")
+	_ = strings.Split(xs[0], "")
+
+	_ = a + g
+	{
+		a := 2
+		_ = a + g
+	}
+
+	var t T
+	_ = t.f
+	t.m()
+}
+`
+
+	fset := token.NewFileSet()
+	f, info := typeCheck(t, fset, src)
+	stmts := f.Decls[len(f.Decls)-1].(*ast.FuncDecl).Body.List
+	rhs := func(i int) ast.Expr {
+		return stmts[i].(*ast.AssignStmt).Rhs[0]
+	}
+	inner := stmts[5].(*ast.BlockStmt).List[1].(*ast.AssignStmt).Rhs[0]
+
+	tests := []struct {
+		name  string
+		x     ast.Expr
+		y     ast.Expr
+		equal bool
+	}{
+		{"alias", rhs(0), rhs(1), true},
+		{"dot import", rhs(0), rhs(2), true},
+		{"dot import reversed", rhs(2), rhs(1), true},
+		{"different funcs", rhs(0), rhs(3), false},
+		{"shadowed var", rhs(4), inner, false},
+		{"same var", rhs(4), rhs(4), true},
+	}
+
+	cmp := New(WithTypesInfo(info, nil))
+	for _, test := range tests {
+		if have := cmp.Expr(test.x, test.y); have != test.equal {
+			t.Errorf("%s: have %v, want %v", test.name, have, test.equal)
+		}
+	}
+
+	if !Expr(rhs(4), inner) {
+		t.Errorf("default comparison must ignore the shadowing")
+	}
+	if Expr(rhs(0), rhs(1)) {
+		t.Errorf("default comparison must compare package names")
+	}
+}
+
+func TestWithTypesInfoDifferentChecks(t *testing.T) {
+	const src1 = `package foo
+
+import "strings"
+
+type T struct{ f int }
+
+func (T) m() {}
+
+func f(xs []string, t T) {
+	_ = strings.Join(xs, "")
+	_ = t.f
+	t.m()
+	_ = len(xs)
+}
+`
+	const src2 = `package foo
+
+import strs "strings"
+
+type T struct{ f int }
+
+func (T) m() {}
+
+func f(xs []string, t T) {
+	_ = strs.Join(xs, "")
+	_ = t.f
+	t.m()
+	_ = len(xs)
+}
+`
+
+	fset := token.NewFileSet()
+	f1, info1 := typeCheck(t, fset, src1)
+	f2, info2 := typeCheck(t, fset, src2)
+	body1 := f1.Decls[len(f1.Decls)-1].(*ast.FuncDecl).Body
+	body2 := f2.Decls[len(f2.Decls)-1].(*ast.FuncDecl).Body
+
+	if !New(WithTypesInfo(info1, info2)).Stmt(body1, body2) {
+		t.Errorf("equal bodies reported as not equal: %v", New(WithTypesInfo(info1, info2)).Explain(body1, body2))
+	}
+	if New(WithTypesInfo(info1, info2)).Stmt(body1.List[0], body2.List[1]) {
+		t.Errorf("different statements reported as equal")
+	}
+	if Stmt(body1, body2) {
+		t.Errorf("default comparison must compare package names")
+	}
+}
+
+func typeCheck(t testing.TB, fset *token.FileSet, src string) (*ast.File, *types.Info) {
+	t.Helper()
+	f, err := parser.ParseFile(fset, "foo.go", src, 0)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("foo", fset, []*ast.File{f}, info); err != nil {
+		t.Fatalf("typecheck: %v", err)
+	}
+	return f, info
+}
+
+func TestWithTypesInfoDifferentChecksLocals(t *testing.T) {
+	const src1 = `package foo
+
+func f(i interface{}) {
+	x := 1
+	_ = x
+	{
+		x := 2
+		_ = x
+	}
+	switch v := i.(type) {
+	case int:
+		_ = v
+	}
+}
+`
+	// The same function laid out differently.
+	const src2 = `package foo
+
+func f(i interface{}) {
+
+	x := 1
+
+	_ = x
+	{ x := 2;  _ = x }
+	switch v := i.(type) { case int: _ = v }
+}
+`
+
+	fset := token.NewFileSet()
+	f1, info1 := typeCheck(t, fset, src1)
+	f2, info2 := typeCheck(t, fset, src2)
+	body1 := f1.Decls[0].(*ast.FuncDecl).Body
+	body2 := f2.Decls[0].(*ast.FuncDecl).Body
+	outer1, outer2 := body1.List[1], body2.List[1]
+	inner1 := body1.List[2].(*ast.BlockStmt).List[1]
+	inner2 := body2.List[2].(*ast.BlockStmt).List[1]
+
+	cmp := New(WithTypesInfo(info1, info1))
+	if cmp.Stmt(outer1, inner1) {
+		t.Errorf("same check: shadowed variable use reported as equal")
+	}
+
+	cmp = New(WithTypesInfo(info1, info2))
+	if !cmp.Stmt(body1, body2) {
+		t.Errorf("equal bodies reported as not equal: %v", cmp.Explain(body1, body2))
+	}
+	if !cmp.Decl(f1.Decls[0], f2.Decls[0]) {
+		t.Errorf("equal functions reported as not equal: %v", cmp.Explain(f1.Decls[0], f2.Decls[0]))
+	}
+
+	cmp = New(WithTypesInfo(info1, info1))
+	if !cmp.Stmt(outer1, outer1) {
+		t.Errorf("same variable use reported as not equal")
+	}
+	cmp = New(WithTypesInfo(info1, info2))
+	if cmp.Stmt(outer1, inner2) {
+		t.Errorf("shadowed variable use reported as equal")
+	}
+
+	// Variables declared outside of the compared nodes are matched
+	// by their positions, so only the same layout matches.
+	f3, info3 := typeCheck(t, fset, src1)
+	body3 := f3.Decls[0].(*ast.FuncDecl).Body
+	cmp = New(WithTypesInfo(info1, info3))
+	if !cmp.Stmt(outer1, body3.List[1]) {
+		t.Errorf("same variable use reported as not equal")
+	}
+	if cmp.Stmt(outer1, body3.List[2].(*ast.BlockStmt).List[1]) {
+		t.Errorf("shadowed variable use reported as equal")
+	}
+	if New(WithTypesInfo(info1, info2)).Stmt(outer1, outer2) {
+		t.Errorf("variables of differently laid out code reported as equal")
+	}
+}